@@ -0,0 +1,275 @@
+// Package backtest evaluates the rules generated for a SLO over real series, read from a TSDB
+// directory of prometheus or from an OpenMetrics dump, to see the alerts they would have fired
+package backtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/globocom/slo-generator/simulate"
+	"github.com/globocom/slo-generator/slo"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Options of a backtest
+type Options struct {
+	DisableTicket      bool
+	EvaluationInterval time.Duration // interval of the groups without one, like the alerts
+	Start              time.Time     // zero to start when the longest window of the rules is full of series
+	End                time.Time     // zero to end at the last sample of the series
+}
+
+// Result is what the alerts of a SLO would have done over a period
+type Result struct {
+	SLO        string
+	Start, End time.Time
+	Firings    []simulate.Firing
+	ErrorRatio float64 // ratio of errors over the period, NaN when the SLO has no error SLI or no requests
+	Budget     float64 // ratio of the error budget consumed over the period
+	Latency    []LatencyResult
+}
+
+// LatencyResult is the latency SLI of a latency objective over the period
+type LatencyResult struct {
+	LE     string
+	Ratio  float64 // ratio of requests faster than LE over the period
+	Budget float64 // ratio of the latency budget of the objective consumed over the period
+}
+
+var errNoSeries = errors.New("no series of the SLIs are found")
+
+// Run evaluates the recording rules and the alerts of a SLO over the series of queryable
+func Run(ctx context.Context, s *slo.SLO, sloClass *slo.Class, queryable storage.Queryable, opts Options) (*Result, error) {
+	if s.HasNativeHistogram() {
+		return nil, fmt.Errorf("SLO %q: %w", s.Name, slo.ErrNativeHistogram)
+	}
+	groups, err := s.GenerateGroupRules(sloClass, opts.DisableTicket)
+	if err != nil {
+		return nil, err
+	}
+	// alerts may compare with the objectives recorded by the budget rules
+	budgetGroup, err := s.GenerateBudgetGroup(sloClass, opts.DisableTicket)
+	if err != nil {
+		return nil, err
+	}
+	if len(budgetGroup.Rules) > 0 {
+		groups = append(groups, budgetGroup)
+	}
+	alerts, err := s.GenerateAlertRules(sloClass, opts.DisableTicket)
+	if err != nil {
+		return nil, err
+	}
+	groups = append(groups, rulefmt.RuleGroup{Name: "slo:" + s.Name + ":alert", Rules: alerts})
+	groups, err = simulate.RulesFor(groups, func(rulefmt.RuleNode) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{SLO: s.Name, Start: opts.Start, End: opts.End, ErrorRatio: math.NaN()}
+	if result.Start.IsZero() || result.End.IsZero() {
+		first, last, err := seriesRange(ctx, queryable, groups)
+		if err != nil {
+			return nil, fmt.Errorf("SLO %q: %w", s.Name, err)
+		}
+		if result.Start.IsZero() {
+			result.Start = first.Add(simulate.LongestRange(groups))
+		}
+		if result.End.IsZero() {
+			result.End = last
+		}
+	}
+	if !result.Start.Before(result.End) {
+		return nil, fmt.Errorf("SLO %q: the period from %s to %s is empty, the series may be shorter than the longest window of the rules, %s",
+			s.Name, formatTime(result.Start), formatTime(result.End), model.Duration(simulate.LongestRange(groups)))
+	}
+
+	evaluator, err := simulate.NewEvaluator(groups, queryable, opts.EvaluationInterval)
+	if err != nil {
+		return nil, err
+	}
+	result.Firings, err = evaluator.Run(ctx, result.Start, result.End)
+	if err != nil {
+		return nil, fmt.Errorf("SLO %q: %w", s.Name, err)
+	}
+
+	period := result.End.Sub(result.Start)
+	window := model.Duration(period).String()
+	if s.ErrorRateRecord.HasSLI() {
+		expr := s.ErrorRateRecord.ComputeExpr(window, "")
+		if s.ErrorRateRecord.IsEventBased() {
+			errorsExpr, requestsExpr := s.ErrorRateRecord.ComputeEvents(window)
+			expr = fmt.Sprintf("(%s) / (%s)", errorsExpr, requestsExpr)
+		}
+
+		vector, err := evaluator.Query(ctx, expr, result.End)
+		if err != nil {
+			return nil, fmt.Errorf("SLO %q: error SLI: %w", s.Name, err)
+		}
+		if len(vector) == 1 {
+			budgetRatio, budgetWindow := s.ErrorBudget(sloClass)
+			result.ErrorRatio = vector[0].V
+			result.Budget = result.ErrorRatio * float64(period) / (budgetRatio * float64(budgetWindow))
+		}
+	}
+
+	targets, budgetWindow := s.LatencyBudget(sloClass)
+	for _, target := range targets {
+		// the latency SLI is empty without latencyRecord or latencyHistogram
+		expr := s.ComputeLatencyExpr(window, target.LE)
+		if expr == "" {
+			break
+		}
+		vector, err := evaluator.Query(ctx, expr, result.End)
+		if err != nil {
+			return nil, fmt.Errorf("SLO %q: latency SLI of le %s: %w", s.Name, target.LE, err)
+		}
+		// targets without requests over the period are not reported
+		if len(vector) != 1 || math.IsNaN(vector[0].V) {
+			continue
+		}
+		result.Latency = append(result.Latency, LatencyResult{
+			LE:     target.LE,
+			Ratio:  vector[0].V,
+			Budget: (1 - vector[0].V) * float64(period) / ((1 - target.Target.Ratio()) * float64(budgetWindow)),
+		})
+	}
+
+	return result, nil
+}
+
+// seriesRange returns the times of the first and the last samples of the series read by the rules,
+// which are not recorded by them
+func seriesRange(ctx context.Context, queryable storage.Queryable, groups []rulefmt.RuleGroup) (time.Time, time.Time, error) {
+	recorded := map[string]bool{}
+	for _, g := range groups {
+		for _, r := range g.Rules {
+			recorded[r.Record.Value] = true
+		}
+	}
+
+	var selectors [][]*labels.Matcher
+	for _, g := range groups {
+		for _, r := range g.Rules {
+			expr, err := parser.ParseExpr(r.Expr.Value)
+			if err != nil {
+				return time.Time{}, time.Time{}, err
+			}
+			parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+				if selector, ok := node.(*parser.VectorSelector); ok && !recorded[selector.Name] {
+					selectors = append(selectors, selector.LabelMatchers)
+				}
+				return nil
+			})
+		}
+	}
+
+	querier, err := queryable.Querier(ctx, math.MinInt64, math.MaxInt64)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	defer querier.Close()
+
+	first, last := int64(math.MaxInt64), int64(math.MinInt64)
+	for _, matchers := range selectors {
+		set := querier.Select(false, nil, matchers...)
+		for set.Next() {
+			it := set.At().Iterator()
+			for it.Next() {
+				t, _ := it.At()
+				if t < first {
+					first = t
+				}
+				if t > last {
+					last = t
+				}
+			}
+		}
+		if err := set.Err(); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if first > last {
+		return time.Time{}, time.Time{}, errNoSeries
+	}
+
+	return timestamp(first), timestamp(last), nil
+}
+
+func timestamp(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC()
+}
+
+// Write writes the results: one line by alert firing with its severity, start, end and duration,
+// then the error SLI, the latency SLI of each latency objective and the budgets they consumed over
+// the period, and the number of alerts by severity
+func Write(w io.Writer, results []*Result) error {
+	for _, result := range results {
+		if _, err := fmt.Fprintf(w, "SLO %q, from %s to %s:\n", result.SLO, formatTime(result.Start), formatTime(result.End)); err != nil {
+			return err
+		}
+
+		counts := map[string]int{}
+		var severities []string
+		for _, firing := range result.Firings {
+			severity := firing.Labels.Get("severity")
+			if counts[severity] == 0 {
+				severities = append(severities, severity)
+			}
+			counts[severity]++
+
+			end, duration := "still firing", ""
+			if !firing.End.IsZero() {
+				end, duration = formatTime(firing.End), model.Duration(firing.End.Sub(firing.Start)).String()
+			}
+			if _, err := fmt.Fprintf(w, "  %-6s %s  %-20s  %-8s %s\n", severity, formatTime(firing.Start), end, duration, firing.Alert); err != nil {
+				return err
+			}
+		}
+
+		if !math.IsNaN(result.ErrorRatio) {
+			if _, err := fmt.Fprintf(w, "  errors: %s of requests, %s of the budget consumed\n", formatPercent(result.ErrorRatio), formatPercent(result.Budget)); err != nil {
+				return err
+			}
+		}
+		for _, latency := range result.Latency {
+			if _, err := fmt.Fprintf(w, "  latency under %s: %s of requests, %s of the budget consumed\n", latency.LE, formatPercent(latency.Ratio), formatPercent(latency.Budget)); err != nil {
+				return err
+			}
+		}
+
+		summary := "no alert fired"
+		for i, severity := range severities {
+			if i == 0 {
+				summary = ""
+			} else {
+				summary += ", "
+			}
+			summary += fmt.Sprintf("%d %s", counts[severity], severity)
+			if counts[severity] > 1 {
+				summary += "s"
+			}
+		}
+		if _, err := fmt.Fprintf(w, "  alerts: %s\n", summary); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatPercent(ratio float64) string {
+	return strconv.FormatFloat(math.Round(ratio*100*1e4)/1e4, 'f', -1, 64) + "%"
+}
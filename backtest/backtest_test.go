@@ -0,0 +1,208 @@
+package backtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/globocom/slo-generator/slo"
+	"github.com/stretchr/testify/assert"
+)
+
+var simpleSLO = []byte(`
+slos:
+  - name: api
+    objectives:
+      availability: 99
+    errorRateRecord:
+      alertMethod: simple
+      alertWindow: 1h
+      burnRate: 10
+      severity: page
+      badEvents: 'http_requests_total{job="api", code=~"5.."}'
+      totalEvents: 'http_requests_total{job="api"}'
+`)
+
+// dump returns 5h of counters scraped every minute, with 1000 requests by minute
+// and 50% of errors during the 30m after 2h
+func dump() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# TYPE http_requests counter\n")
+	ok, failed := 0.0, 0.0
+	for minute := 0; minute <= 5*60; minute++ {
+		buf.WriteString(fmt.Sprintf("http_requests_total{job=\"api\",code=\"200\"} %g %d\n", ok, minute*60))
+		buf.WriteString(fmt.Sprintf("http_requests_total{job=\"api\",code=\"500\"} %g %d\n", failed, minute*60))
+		if minute >= 120 && minute < 150 {
+			ok, failed = ok+500, failed+500
+		} else {
+			ok += 1000
+		}
+	}
+	buf.WriteString("# EOF\n")
+
+	return buf.Bytes()
+}
+
+func TestRun(t *testing.T) {
+	spec, err := slo.ParseSLOSpec(simpleSLO, "slo.yml", true)
+	assert.NoError(t, err)
+
+	storage, err := LoadOpenMetrics(dump(), "dump.txt")
+	assert.NoError(t, err)
+
+	result, err := Run(context.Background(), &spec.SLOS[0], nil, storage, Options{EvaluationInterval: time.Minute})
+	assert.NoError(t, err)
+
+	start := time.Unix(0, 0).UTC()
+	assert.Equal(t, start.Add(time.Hour), result.Start)
+	assert.Equal(t, start.Add(5*time.Hour), result.End)
+	// the error ratio of the last hour is above 10% after 12m of 50% errors,
+	// and back to 10% when only 12m of errors are left in the hour
+	assert.Len(t, result.Firings, 1)
+	assert.Equal(t, "slo:api.errors.page", result.Firings[0].Alert)
+	assert.Equal(t, start.Add(2*time.Hour+13*time.Minute), result.Firings[0].Start)
+	assert.Equal(t, start.Add(3*time.Hour+18*time.Minute), result.Firings[0].End)
+
+	// 15000 errors of the 240000 requests of the last 4h
+	assert.InDelta(t, 15000.0/240000, result.ErrorRatio, 1e-9)
+	assert.InDelta(t, 15000.0/240000*4/(0.01*30*24), result.Budget, 1e-9)
+
+	var output bytes.Buffer
+	assert.NoError(t, Write(&output, []*Result{result}))
+	assert.Equal(t, `SLO "api", from 1970-01-01T01:00:00Z to 1970-01-01T05:00:00Z:
+  page   1970-01-01T02:13:00Z  1970-01-01T03:18:00Z  1h5m     slo:api.errors.page
+  errors: 6.25% of requests, 3.4722% of the budget consumed
+  alerts: 1 page
+`, output.String())
+}
+
+func TestRunWithLatency(t *testing.T) {
+	spec, err := slo.ParseSLOSpec([]byte(`
+slos:
+  - name: api
+    objectives:
+      latency:
+        - le: 0.25
+          target: 95
+    latencyRecord:
+      alertMethod: simple
+      alertWindow: 1h
+    latencyHistogram:
+      metric: http_request_duration_seconds
+      selector: '{job="api"}'
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	// 1000 requests by minute, 20 slower than 0.25s, 200 during the 30m after 2h
+	var buf bytes.Buffer
+	buf.WriteString("# TYPE http_request_duration_seconds histogram\n")
+	fast, total := 0.0, 0.0
+	for minute := 0; minute <= 5*60; minute++ {
+		buf.WriteString(fmt.Sprintf("http_request_duration_seconds_bucket{job=\"api\",le=\"0.25\"} %g %d\n", fast, minute*60))
+		buf.WriteString(fmt.Sprintf("http_request_duration_seconds_bucket{job=\"api\",le=\"+Inf\"} %g %d\n", total, minute*60))
+		buf.WriteString(fmt.Sprintf("http_request_duration_seconds_count{job=\"api\"} %g %d\n", total, minute*60))
+		if minute >= 120 && minute < 150 {
+			fast += 800
+		} else {
+			fast += 980
+		}
+		total += 1000
+	}
+	buf.WriteString("# EOF\n")
+
+	storage, err := LoadOpenMetrics(buf.Bytes(), "dump.txt")
+	assert.NoError(t, err)
+
+	result, err := Run(context.Background(), &spec.SLOS[0], nil, storage, Options{EvaluationInterval: time.Minute})
+	assert.NoError(t, err)
+	assert.True(t, math.IsNaN(result.ErrorRatio))
+
+	// the ratio of the last hour is under 95% after 11m of 20% slow requests
+	assert.Len(t, result.Firings, 1)
+
+	// 10200 slow requests of the 240000 requests of the last 4h
+	if assert.Len(t, result.Latency, 1) {
+		assert.Equal(t, "0.25", result.Latency[0].LE)
+		assert.InDelta(t, 1-10200.0/240000, result.Latency[0].Ratio, 1e-9)
+		assert.InDelta(t, 10200.0/240000*4/(0.05*30*24), result.Latency[0].Budget, 1e-9)
+	}
+
+	var output bytes.Buffer
+	assert.NoError(t, Write(&output, []*Result{result}))
+	assert.Equal(t, `SLO "api", from 1970-01-01T01:00:00Z to 1970-01-01T05:00:00Z:
+  page   1970-01-01T02:11:00Z  1970-01-01T03:20:00Z  1h9m     slo:api.latency.page
+  latency under 0.25: 95.75% of requests, 0.4722% of the budget consumed
+  alerts: 1 page
+`, output.String())
+}
+
+func TestRunWithPeriod(t *testing.T) {
+	spec, err := slo.ParseSLOSpec(simpleSLO, "slo.yml", true)
+	assert.NoError(t, err)
+
+	storage, err := LoadOpenMetrics(dump(), "dump.txt")
+	assert.NoError(t, err)
+
+	start := time.Unix(0, 0).UTC()
+	result, err := Run(context.Background(), &spec.SLOS[0], nil, storage, Options{
+		EvaluationInterval: time.Minute,
+		Start:              start.Add(2*time.Hour + 30*time.Minute),
+		End:                start.Add(3 * time.Hour),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, result.Firings, 1)
+	assert.True(t, result.Firings[0].End.IsZero())
+	assert.Equal(t, 0.0, result.ErrorRatio)
+
+	var output bytes.Buffer
+	assert.NoError(t, Write(&output, []*Result{result}))
+	assert.Contains(t, output.String(), "still firing")
+
+	_, err = Run(context.Background(), &spec.SLOS[0], nil, storage, Options{
+		EvaluationInterval: time.Minute,
+		Start:              start.Add(3 * time.Hour),
+		End:                start.Add(3 * time.Hour),
+	})
+	assert.Error(t, err)
+}
+
+func TestRunWithoutSeries(t *testing.T) {
+	spec, err := slo.ParseSLOSpec(simpleSLO, "slo.yml", true)
+	assert.NoError(t, err)
+
+	storage, err := LoadOpenMetrics([]byte("# TYPE other counter\nother_total 1 0\n# EOF\n"), "dump.txt")
+	assert.NoError(t, err)
+
+	_, err = Run(context.Background(), &spec.SLOS[0], nil, storage, Options{EvaluationInterval: time.Minute})
+	assert.EqualError(t, err, `SLO "api": no series of the SLIs are found`)
+}
+
+func TestRunWithNativeHistogram(t *testing.T) {
+	spec, err := slo.ParseSLOSpec([]byte(`
+slos:
+  - name: api
+    objectives:
+      latency:
+        - le: 0.25
+          target: 95
+    latencyHistogram:
+      metric: http_request_duration_seconds
+      native: true
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	storage, err := LoadOpenMetrics(dump(), "dump.txt")
+	assert.NoError(t, err)
+
+	_, err = Run(context.Background(), &spec.SLOS[0], nil, storage, Options{EvaluationInterval: time.Minute})
+	assert.True(t, errors.Is(err, slo.ErrNativeHistogram))
+}
+
+func TestLoadOpenMetricsWithoutTimestamp(t *testing.T) {
+	_, err := LoadOpenMetrics([]byte("# TYPE requests counter\nrequests_total 1\n# EOF\n"), "dump.txt")
+	assert.EqualError(t, err, `dump.txt: sample requests_total has no timestamp`)
+}
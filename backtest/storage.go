@@ -0,0 +1,87 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/globocom/slo-generator/simulate"
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/textparse"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+)
+
+// LoadOpenMetrics reads the samples of an OpenMetrics text dump into memory,
+// every sample must have its timestamp
+func LoadOpenMetrics(content []byte, file string) (*simulate.Storage, error) {
+	memory := simulate.NewStorage()
+	parser := textparse.NewOpenMetricsParser(content)
+
+	for {
+		entry, err := parser.Next()
+		if errors.Is(err, io.EOF) {
+			return memory, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if entry != textparse.EntrySeries {
+			continue
+		}
+
+		series, ts, value := parser.Series()
+		if ts == nil {
+			return nil, fmt.Errorf("%s: sample %s has no timestamp", file, series)
+		}
+		var lbls labels.Labels
+		parser.Metric(&lbls)
+		memory.Add(lbls, *ts, value)
+	}
+}
+
+// OpenTSDB opens the data directory of prometheus without writing it, it must be closed after use.
+// Without a WAL, like the blocks created by promtool from a dump, only the blocks are read
+func OpenTSDB(dir string) (storage.Queryable, io.Closer, error) {
+	db, err := tsdb.OpenDBReadOnly(dir, log.NewNopLogger())
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "wal")); err == nil {
+		return db, db, nil
+	}
+
+	blocks, err := db.Blocks()
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return blocksQueryable(blocks), db, nil
+}
+
+// blocksQueryable merges the blocks of a TSDB
+type blocksQueryable []tsdb.BlockReader
+
+func (b blocksQueryable) Querier(_ context.Context, mint, maxt int64) (storage.Querier, error) {
+	var queriers []storage.Querier
+	for _, block := range b {
+		if meta := block.Meta(); meta.MaxTime < mint || meta.MinTime > maxt {
+			continue
+		}
+		querier, err := tsdb.NewBlockQuerier(block, mint, maxt)
+		if err != nil {
+			for _, q := range queriers {
+				q.Close()
+			}
+			return nil, err
+		}
+		queriers = append(queriers, querier)
+	}
+
+	return storage.NewMergeQuerier(queriers, nil, storage.ChainedSeriesMerge), nil
+}
@@ -0,0 +1,626 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/globocom/slo-generator/backtest"
+	"github.com/globocom/slo-generator/diff"
+	"github.com/globocom/slo-generator/simulate"
+	"github.com/globocom/slo-generator/slo"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	"github.com/prometheus/prometheus/storage"
+)
+
+var errNoSLOPath = errors.New("slo.path is a required param")
+
+// specFlags are the flags of commands reading SLOs and classes
+type specFlags struct {
+	sloPaths     stringList
+	classesPaths stringList
+	strict       bool
+}
+
+func (f *specFlags) register(flags *flag.FlagSet) {
+	flags.Var(&f.sloPaths, "slo.path", "A YML file, directory or glob describing SLOs, may be repeated")
+	flags.Var(&f.classesPaths, "classes.path", "A YML file, directory or glob describing SLOs classes, may be repeated (optional)")
+	flags.BoolVar(&f.strict, "strict", true, "Reject unknown fields in SLOs and classes files, use -strict=false for older files")
+}
+
+// load reads the SLO specifications, adding the shared classes to each one
+func (f *specFlags) load() ([]*slo.SLOSpec, error) {
+	if len(f.sloPaths) == 0 {
+		return nil, errNoSLOPath
+	}
+
+	specs, err := slo.ReadSLOSpecs(f.sloPaths, f.strict)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f.classesPaths) > 0 {
+		sharedClasses, err := slo.ReadClasses(f.classesPaths, f.strict)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, spec := range specs {
+			for _, warning := range spec.AddSharedClasses(sharedClasses) {
+				log.Printf("warning: %s", warning)
+			}
+		}
+	}
+
+	return specs, nil
+}
+
+// loadError returns the exit code of specifications that can not be loaded: files that can not
+// be read are errors running the generator, files that can not be decoded are problems in SLOs
+func loadError(err error) int {
+	var pathErr *os.PathError
+	if errors.Is(err, errNoSLOPath) || errors.As(err, &pathErr) {
+		return fail(exitError, err)
+	}
+
+	return fail(exitProblems, err)
+}
+
+// generateFlags are the flags of the generation of rules
+type generateFlags struct {
+	ruleOutput    string
+	outputDir     string
+	outputBy      string
+	k8s           bool
+	k8sLabels     string
+	disableTicket bool
+	testsOutput   string
+}
+
+func (f *generateFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.ruleOutput, "rule.output", "", "Output to describe a prometheus rules")
+	flags.StringVar(&f.outputDir, "output.dir", "", "Directory where rules are written split by SLO file or by team, see -output.by")
+	flags.StringVar(&f.outputBy, "output.by", "file", "How rules are split in -output.dir: file or team")
+	flags.BoolVar(&f.k8s, "kubernetes", false, "Generates prometheus-operator YAML")
+	flags.StringVar(&f.k8sLabels, "kubernetes-labels", "", "Add some labels in generated resource")
+	flags.BoolVar(&f.disableTicket, "disable.ticket", false, "Disable generation of alerts of kind ticket")
+	flags.StringVar(&f.testsOutput, "tests.output", "", "Output of promtool unit tests of the error alerts, with -rule.output or -output.dir (optional)")
+}
+
+func runGenerate(args []string) int {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	spec := &specFlags{}
+	spec.register(flags)
+	gen := &generateFlags{}
+	gen.register(flags)
+	flags.Parse(args)
+
+	return generateRules(spec, gen)
+}
+
+// generateRules writes the rules of SLOs to the standard output, to -rule.output or to -output.dir
+func generateRules(spec *specFlags, gen *generateFlags) int {
+	if gen.ruleOutput != "" && gen.outputDir != "" {
+		return fail(exitError, errors.New("rule.output and output.dir can not be used together"))
+	}
+	if gen.outputBy != "file" && gen.outputBy != "team" {
+		return fail(exitError, fmt.Errorf("output.by must be file or team, got %q", gen.outputBy))
+	}
+	if gen.testsOutput != "" && gen.k8s {
+		return fail(exitError, errors.New("tests.output can not be used with kubernetes, promtool reads rules files"))
+	}
+	if gen.testsOutput != "" && gen.ruleOutput == "" && gen.outputDir == "" {
+		return fail(exitError, errors.New("tests.output needs the rules written to rule.output or output.dir"))
+	}
+
+	opts := generateOpts{
+		kubernetes:       gen.k8s,
+		kubernetesLabels: map[string]string{},
+		disableTicket:    gen.disableTicket,
+	}
+	if gen.k8s && gen.k8sLabels != "" {
+		labels, err := parseLabels(gen.k8sLabels)
+		if err != nil {
+			return fail(exitError, err)
+		}
+		opts.kubernetesLabels = labels
+	}
+
+	specs, err := spec.load()
+	if err != nil {
+		return loadError(err)
+	}
+	logWarnings(specs)
+
+	slos, err := classify(specs)
+	if err != nil {
+		return fail(exitProblems, err)
+	}
+
+	if gen.outputDir != "" {
+		files, paths := splitOutput(slos, gen.outputDir, gen.outputBy)
+		for _, path := range paths {
+			// rules are generated before the file is written, so only I/O errors fail with exitError
+			var content bytes.Buffer
+			if err := generate(&content, files[path], opts); err != nil {
+				return fail(exitProblems, err)
+			}
+			if err := writeFile(path, content.Bytes()); err != nil {
+				return fail(exitError, err)
+			}
+		}
+		log.Printf("generated %d files in %q", len(paths), gen.outputDir)
+		return generateTests(gen, slos, paths)
+	}
+
+	var output io.Writer = os.Stdout
+	if gen.ruleOutput != "" {
+		targetFile, err := os.Create(gen.ruleOutput)
+		if err != nil {
+			return fail(exitError, err)
+		}
+		defer targetFile.Close()
+		output = targetFile
+	}
+
+	if err := generate(output, slos, opts); err != nil {
+		return fail(exitProblems, err)
+	}
+	if gen.ruleOutput == "" {
+		return exitOK
+	}
+	if gen.k8s {
+		log.Printf("generated a kubernetes manifest record in %q", gen.ruleOutput)
+	} else {
+		log.Printf("generated a SLO record in %q", gen.ruleOutput)
+	}
+
+	return generateTests(gen, slos, []string{gen.ruleOutput})
+}
+
+// generateTests writes the unit tests of the rules files generated to -tests.output, when given
+func generateTests(gen *generateFlags, slos []classifiedSLO, ruleFiles []string) int {
+	if gen.testsOutput == "" {
+		return exitOK
+	}
+
+	count, err := writeTests(gen.testsOutput, slos, ruleFiles, gen.disableTicket)
+	if err != nil {
+		return fail(exitError, err)
+	}
+	log.Printf("generated %d unit tests in %q, run them with promtool test rules", count, gen.testsOutput)
+
+	return exitOK
+}
+
+// logWarnings logs the common mistakes found in the expressions of SLOs
+func logWarnings(specs []*slo.SLOSpec) {
+	for _, spec := range specs {
+		for _, s := range spec.SLOS {
+			for _, warning := range s.Warnings() {
+				log.Printf("warning: %s", warning)
+			}
+		}
+	}
+}
+
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	spec := &specFlags{}
+	spec.register(flags)
+	flags.Parse(args)
+
+	return validateSLOs(spec)
+}
+
+// validateSLOs reports every problem found in the specifications
+func validateSLOs(spec *specFlags) int {
+	specs, err := spec.load()
+	if err != nil {
+		return loadError(err)
+	}
+	logWarnings(specs)
+
+	code := exitOK
+	count := 0
+	for _, spec := range specs {
+		if err := spec.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = exitProblems
+		}
+		count += len(spec.SLOS)
+	}
+	if code == exitOK {
+		log.Printf("%d SLOs are valid", count)
+	}
+
+	return code
+}
+
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	spec := &specFlags{}
+	spec.register(flags)
+	flags.Parse(args)
+
+	specs, err := spec.load()
+	if err != nil {
+		return loadError(err)
+	}
+
+	code := exitOK
+	for _, spec := range specs {
+		if err := spec.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = exitProblems
+			continue
+		}
+
+		for _, s := range spec.SLOS {
+			sloClass, err := spec.Classes.FindClass(s.Class)
+			if err != nil {
+				return fail(exitProblems, err)
+			}
+
+			for _, warning := range append(s.Warnings(), s.Lint(sloClass)...) {
+				fmt.Println(warning)
+				code = exitProblems
+			}
+		}
+	}
+
+	return code
+}
+
+func runExplain(args []string) int {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of explain: explain [flags] [slo...]\n")
+		flags.PrintDefaults()
+	}
+	spec := &specFlags{}
+	spec.register(flags)
+	disableTicket := flags.Bool("disable.ticket", false, "Disable generation of alerts of kind ticket")
+	flags.Parse(args)
+
+	return explainSLOs(spec, flags.Args(), *disableTicket)
+}
+
+// explainSLOs shows where the effective values of SLOs were defined, the thresholds of their
+// alerts and the rules generated, names filter the SLOs explained
+func explainSLOs(spec *specFlags, names []string, disableTicket bool) int {
+	specs, err := spec.load()
+	if err != nil {
+		return loadError(err)
+	}
+
+	slos, err := classify(specs)
+	if err != nil {
+		return fail(exitProblems, err)
+	}
+
+	found := map[string]bool{}
+	for _, s := range slos {
+		if len(names) > 0 && !contains(names, s.slo.Name) {
+			continue
+		}
+		found[s.slo.Name] = true
+
+		explanations, err := s.slo.Explain(findSpec(specs, s.slo.Name).Classes)
+		if err != nil {
+			return fail(exitProblems, fmt.Errorf("Could not explain SLO: %q, err: %q", s.slo.Name, err.Error()))
+		}
+		groups, err := sloRuleGroups(s, disableTicket)
+		if err != nil {
+			return fail(exitProblems, err)
+		}
+
+		fmt.Printf("SLO %q:\n", s.slo.Name)
+		for _, explanation := range explanations {
+			fmt.Printf("  %s\n", explanation)
+		}
+		if thresholds := s.slo.AlertThresholds(s.class, disableTicket); len(thresholds) > 0 {
+			fmt.Println("  alerts:")
+			for _, threshold := range thresholds {
+				fmt.Printf("    %s\n", threshold)
+			}
+		}
+		fmt.Println("  rules:")
+		for _, group := range groups {
+			fmt.Printf("    %s: %s\n", group.Name, strings.Join(ruleNames(group), ", "))
+		}
+	}
+
+	for _, name := range names {
+		if !found[name] {
+			return fail(exitError, fmt.Errorf("SLO %q is not found", name))
+		}
+	}
+
+	return exitOK
+}
+
+func findSpec(specs []*slo.SLOSpec, name string) *slo.SLOSpec {
+	for _, spec := range specs {
+		for _, s := range spec.SLOS {
+			if s.Name == name {
+				return spec
+			}
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ruleNames returns the names recorded or alerted by a group, each one once
+func ruleNames(group rulefmt.RuleGroup) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, rule := range group.Rules {
+		name := rule.Record.Value
+		if name == "" {
+			name = rule.Alert.Value
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func runDiff(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	spec := &specFlags{}
+	spec.register(flags)
+	rulesPath := flags.String("rules.path", "", "The rules file, or the PrometheusRule manifests, generated before")
+	disableTicket := flags.Bool("disable.ticket", false, "Disable generation of alerts of kind ticket")
+	flags.Parse(args)
+
+	if *rulesPath == "" {
+		return fail(exitError, errors.New("rules.path is a required param"))
+	}
+
+	content, err := os.ReadFile(*rulesPath)
+	if err != nil {
+		return fail(exitError, err)
+	}
+	existing, err := diff.Load(content, *rulesPath)
+	if err != nil {
+		return fail(exitError, err)
+	}
+
+	specs, err := spec.load()
+	if err != nil {
+		return loadError(err)
+	}
+	slos, err := classify(specs)
+	if err != nil {
+		return fail(exitProblems, err)
+	}
+
+	var generated []rulefmt.RuleGroup
+	for _, s := range slos {
+		groups, err := sloRuleGroups(s, *disableTicket)
+		if err != nil {
+			return fail(exitProblems, err)
+		}
+		generated = append(generated, groups...)
+	}
+
+	changes := diff.Compare(existing, diff.FromGroups(generated))
+	if err := diff.Write(os.Stdout, changes); err != nil {
+		return fail(exitError, err)
+	}
+	if len(changes) > 0 {
+		log.Printf("%d rules differ from %q, regenerate them", len(changes), *rulesPath)
+		return exitProblems
+	}
+
+	return exitOK
+}
+
+func runSimulate(args []string) int {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of simulate: simulate [flags] [slo...]\n")
+		flags.PrintDefaults()
+	}
+	spec := &specFlags{}
+	spec.register(flags)
+	profilePath := flags.String("profile.path", "", "A YML file describing the error profiles of the incidents simulated")
+	opts := simulate.Options{}
+	flags.BoolVar(&opts.DisableTicket, "disable.ticket", false, "Disable generation of alerts of kind ticket")
+	flags.DurationVar(&opts.EvaluationInterval, "evaluation.interval", time.Minute, "Interval of the groups of rules without one, like the alerts")
+	flags.DurationVar(&opts.ScrapeInterval, "scrape.interval", time.Minute, "Interval of the samples of the synthetic counters")
+	flags.Parse(args)
+
+	if *profilePath == "" {
+		return fail(exitError, errors.New("profile.path is a required param"))
+	}
+	if opts.EvaluationInterval <= 0 || opts.ScrapeInterval <= 0 {
+		return fail(exitError, errors.New("evaluation.interval and scrape.interval must be positive"))
+	}
+	content, err := os.ReadFile(*profilePath)
+	if err != nil {
+		return fail(exitError, err)
+	}
+	profiles, err := simulate.ParseProfiles(content, *profilePath)
+	if err != nil {
+		return fail(exitError, err)
+	}
+
+	return simulateSLOs(spec, flags.Args(), profiles, opts)
+}
+
+// simulateSLOs runs every profile on the error alerts of SLOs, names filter the SLOs simulated.
+// SLOs that can not be simulated are skipped with a warning, unless they are named
+func simulateSLOs(spec *specFlags, names []string, profiles []simulate.Profile, opts simulate.Options) int {
+	specs, err := spec.load()
+	if err != nil {
+		return loadError(err)
+	}
+	slos, err := classify(specs)
+	if err != nil {
+		return fail(exitProblems, err)
+	}
+
+	found := map[string]bool{}
+	var results []*simulate.Result
+	for _, s := range slos {
+		if len(names) > 0 && !contains(names, s.slo.Name) {
+			continue
+		}
+		found[s.slo.Name] = true
+
+		for i := range profiles {
+			result, err := simulate.Run(context.Background(), &s.slo, s.class, &profiles[i], opts)
+			if err != nil && len(names) > 0 {
+				return fail(exitProblems, err)
+			}
+			if err != nil {
+				log.Printf("warning: %s, it is not simulated", err)
+				break
+			}
+			results = append(results, result)
+		}
+	}
+
+	for _, name := range names {
+		if !found[name] {
+			return fail(exitError, fmt.Errorf("SLO %q is not found", name))
+		}
+	}
+
+	if err := simulate.Write(os.Stdout, results); err != nil {
+		return fail(exitError, err)
+	}
+
+	return exitOK
+}
+
+func runBacktest(args []string) int {
+	flags := flag.NewFlagSet("backtest", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of backtest: backtest [flags] [slo...]\n")
+		flags.PrintDefaults()
+	}
+	spec := &specFlags{}
+	spec.register(flags)
+	tsdbPath := flags.String("tsdb.path", "", "A data directory of prometheus with the series of the SLIs")
+	openMetricsPath := flags.String("openmetrics.path", "", "An OpenMetrics text dump with the series of the SLIs, every sample with its timestamp")
+	start := flags.String("start", "", "Start of the period evaluated, RFC3339, by default when the longest window of the rules is full of series")
+	end := flags.String("end", "", "End of the period evaluated, RFC3339, by default the last sample of the series")
+	opts := backtest.Options{}
+	flags.BoolVar(&opts.DisableTicket, "disable.ticket", false, "Disable generation of alerts of kind ticket")
+	flags.DurationVar(&opts.EvaluationInterval, "evaluation.interval", time.Minute, "Interval of the groups of rules without one, like the alerts")
+	flags.Parse(args)
+
+	if (*tsdbPath == "") == (*openMetricsPath == "") {
+		return fail(exitError, errors.New("one of tsdb.path and openmetrics.path is a required param"))
+	}
+	if opts.EvaluationInterval <= 0 {
+		return fail(exitError, errors.New("evaluation.interval must be positive"))
+	}
+	var err error
+	if opts.Start, err = parseTime("start", *start); err != nil {
+		return fail(exitError, err)
+	}
+	if opts.End, err = parseTime("end", *end); err != nil {
+		return fail(exitError, err)
+	}
+
+	var queryable storage.Queryable
+	if *tsdbPath != "" {
+		db, closer, err := backtest.OpenTSDB(*tsdbPath)
+		if err != nil {
+			return fail(exitError, err)
+		}
+		defer closer.Close()
+		queryable = db
+	} else {
+		content, err := os.ReadFile(*openMetricsPath)
+		if err != nil {
+			return fail(exitError, err)
+		}
+		if queryable, err = backtest.LoadOpenMetrics(content, *openMetricsPath); err != nil {
+			return fail(exitError, err)
+		}
+	}
+
+	return backtestSLOs(spec, flags.Args(), queryable, opts)
+}
+
+func parseTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	return t, nil
+}
+
+// backtestSLOs evaluates the rules of SLOs over the series of queryable, names filter the SLOs evaluated.
+// SLOs that can not be evaluated, like those without series, are skipped with a warning, unless they are named
+func backtestSLOs(spec *specFlags, names []string, queryable storage.Queryable, opts backtest.Options) int {
+	specs, err := spec.load()
+	if err != nil {
+		return loadError(err)
+	}
+	slos, err := classify(specs)
+	if err != nil {
+		return fail(exitProblems, err)
+	}
+
+	found := map[string]bool{}
+	var results []*backtest.Result
+	for _, s := range slos {
+		if len(names) > 0 && !contains(names, s.slo.Name) {
+			continue
+		}
+		found[s.slo.Name] = true
+
+		result, err := backtest.Run(context.Background(), &s.slo, s.class, queryable, opts)
+		if err != nil && len(names) > 0 {
+			return fail(exitProblems, err)
+		}
+		if err != nil {
+			log.Printf("warning: %s, it is not evaluated", err)
+			continue
+		}
+		results = append(results, result)
+	}
+
+	for _, name := range names {
+		if !found[name] {
+			return fail(exitError, fmt.Errorf("SLO %q is not found", name))
+		}
+	}
+
+	if err := backtest.Write(os.Stdout, results); err != nil {
+		return fail(exitError, err)
+	}
+
+	return exitOK
+}
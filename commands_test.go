@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFiles = map[string]string{
+	"good.yml": `
+slos:
+  - name: my-service
+    objectives:
+      availability: 99.9
+      window: 28d
+    labels:
+      team: search
+    annotations:
+      runbook_url: https://wiki.ops/runbooks/my-service
+    errorRateRecord:
+      alertMethod: multi-window
+      expr: sum(rate(http_errors[$window])) / sum(rate(http_total[$window]))
+`,
+	"simple.yml": `
+slos:
+  - name: other-service
+    objectives:
+      availability: 99
+    errorRateRecord:
+      alertMethod: simple
+      alertWindow: 1h
+      expr: sum(rate(http_errors[$window])) / sum(rate(http_total[$window]))
+`,
+	"invalid.yml": `
+slos:
+  - name: my-service
+    objectives:
+      availability: 120
+`,
+	"unknown-method.yml": `
+slos:
+  - name: my-service
+    objectives:
+      availability: 99.9
+    errorRateRecord:
+      alertMethod: unknown
+      expr: sum(rate(http_errors[$window])) / sum(rate(http_total[$window]))
+`,
+	"broken.yml": "slos: [\n",
+}
+
+// writeTestFiles writes the SLO files of the tests to a temporary directory
+func writeTestFiles(t *testing.T) string {
+	dir := t.TempDir()
+	for name, content := range testFiles {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	return dir
+}
+
+func TestCommands(t *testing.T) {
+	dir := writeTestFiles(t)
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	// rules of good.yml, compared by diff
+	assert.Equal(t, exitOK, runGenerate([]string{"-slo.path=" + path("good.yml"), "-rule.output=" + path("rules.yml")}))
+
+	tests := []struct {
+		name     string
+		run      func([]string) int
+		args     []string
+		expected int
+	}{
+		{"generate", runGenerate, []string{"-slo.path=" + path("good.yml"), "-rule.output=" + path("generated.yml")}, exitOK},
+		{"generate without slo.path", runGenerate, nil, exitError},
+		{"generate a missing file", runGenerate, []string{"-slo.path=" + path("missing.yml")}, exitError},
+		{"generate a file not decoded", runGenerate, []string{"-slo.path=" + path("broken.yml")}, exitProblems},
+		{"generate to rule.output and output.dir", runGenerate, []string{"-slo.path=" + path("good.yml"), "-rule.output=" + path("x.yml"), "-output.dir=" + path("out")}, exitError},
+		{"generate by owner", runGenerate, []string{"-slo.path=" + path("good.yml"), "-output.dir=" + path("out"), "-output.by=owner"}, exitError},
+		{"generate to an output.dir not created", runGenerate, []string{"-slo.path=" + path("good.yml"), "-output.dir=" + path("broken.yml")}, exitError},
+		{"generate rules failing to output.dir", runGenerate, []string{"-slo.path=" + path("unknown-method.yml"), "-output.dir=" + path("failed")}, exitProblems},
+		{"generate tests to stdout", runGenerate, []string{"-slo.path=" + path("good.yml"), "-tests.output=" + path("tests.yml")}, exitError},
+		{"validate", runValidate, []string{"-slo.path=" + path("good.yml")}, exitOK},
+		{"validate an invalid SLO", runValidate, []string{"-slo.path=" + path("invalid.yml")}, exitProblems},
+		{"validate a missing file", runValidate, []string{"-slo.path=" + path("missing.yml")}, exitError},
+		{"lint", runLint, []string{"-slo.path=" + path("good.yml")}, exitOK},
+		{"lint simple alerts", runLint, []string{"-slo.path=" + path("simple.yml")}, exitProblems},
+		{"diff", runDiff, []string{"-slo.path=" + path("good.yml"), "-rules.path=" + path("rules.yml")}, exitOK},
+		{"diff other rules", runDiff, []string{"-slo.path=" + path("simple.yml"), "-rules.path=" + path("rules.yml")}, exitProblems},
+		{"diff without rules.path", runDiff, []string{"-slo.path=" + path("good.yml")}, exitError},
+		{"diff a missing rules file", runDiff, []string{"-slo.path=" + path("good.yml"), "-rules.path=" + path("missing.yml")}, exitError},
+		{"legacy validate", runLegacy, []string{"-validate", "-slo.path=" + path("invalid.yml")}, exitProblems},
+		{"legacy generate", runLegacy, []string{"-slo.path=" + path("good.yml"), "-rule.output=" + path("legacy.yml")}, exitOK},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.run(test.args), test.name)
+	}
+
+	// commands failing on their flags or on generation write nothing
+	_, err := os.Stat(path("out"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path("failed"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path("legacy.yml"))
+	assert.NoError(t, err)
+}
+
+func TestRunLegacyRouting(t *testing.T) {
+	dir := writeTestFiles(t)
+	output := filepath.Join(dir, "rules.yml")
+
+	// -validate and -explain do not generate rules
+	assert.Equal(t, exitOK, runLegacy([]string{"-validate", "-slo.path=" + filepath.Join(dir, "good.yml"), "-rule.output=" + output}))
+	assert.Equal(t, exitOK, runLegacy([]string{"-explain", "-slo.path=" + filepath.Join(dir, "good.yml"), "-rule.output=" + output}))
+	_, err := os.Stat(output)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Equal(t, exitOK, runLegacy([]string{"-slo.path=" + filepath.Join(dir, "good.yml"), "-rule.output=" + output}))
+	_, err = os.Stat(output)
+	assert.NoError(t, err)
+}
+
+func TestLoadError(t *testing.T) {
+	spec := &specFlags{sloPaths: stringList{filepath.Join(t.TempDir(), "missing.yml")}}
+	_, err := spec.load()
+	assert.Equal(t, exitError, loadError(err))
+
+	_, err = (&specFlags{}).load()
+	assert.Equal(t, exitError, loadError(err))
+
+	dir := writeTestFiles(t)
+	_, err = (&specFlags{sloPaths: stringList{filepath.Join(dir, "broken.yml")}}).load()
+	assert.Equal(t, exitProblems, loadError(err))
+}
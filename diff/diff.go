@@ -0,0 +1,183 @@
+// Package diff compares prometheus rules by their meaning instead of their text: rules are
+// matched by group, name and labels, and changes of thresholds in expressions are told apart
+package diff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+type ChangeType string
+
+const (
+	Added   = ChangeType("+")
+	Removed = ChangeType("-")
+	Changed = ChangeType("~")
+)
+
+// Change is a rule added, removed or changed
+type Change struct {
+	Type   ChangeType
+	Rule   Rule // the rule after the change, or before when it was removed
+	Fields []FieldChange
+}
+
+// FieldChange is a field of a rule that changed, like expr, for or annotations.summary
+type FieldChange struct {
+	Field     string
+	Before    string
+	After     string
+	Threshold bool // a number of the expression changed, and nothing else
+}
+
+func (c FieldChange) String() string {
+	if c.Threshold {
+		return fmt.Sprintf("! %s: %s -> %s", c.Field, c.Before, c.After)
+	}
+
+	return fmt.Sprintf("%s: %q -> %q", c.Field, c.Before, c.After)
+}
+
+// Compare returns the rules added, removed or changed from before to after, sorted by their keys
+func Compare(before, after []Rule) []Change {
+	previous := map[string]Rule{}
+	for _, rule := range before {
+		previous[rule.Key()] = rule
+	}
+
+	var changes []Change
+	current := map[string]bool{}
+	for _, rule := range after {
+		key := rule.Key()
+		current[key] = true
+
+		old, ok := previous[key]
+		if !ok {
+			changes = append(changes, Change{Type: Added, Rule: rule})
+			continue
+		}
+		if fields := compareRules(old, rule); len(fields) > 0 {
+			changes = append(changes, Change{Type: Changed, Rule: rule, Fields: fields})
+		}
+	}
+
+	for _, rule := range before {
+		if !current[rule.Key()] {
+			changes = append(changes, Change{Type: Removed, Rule: rule})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Rule.Key() < changes[j].Rule.Key()
+	})
+
+	return changes
+}
+
+// Write writes the changes, one rule by line followed by its fields changed,
+// threshold changes are marked with !
+func Write(w io.Writer, changes []Change) error {
+	for _, change := range changes {
+		if _, err := fmt.Fprintf(w, "%s %s\n", change.Type, change.Rule.Key()); err != nil {
+			return err
+		}
+		for _, field := range change.Fields {
+			if _, err := fmt.Fprintf(w, "    %s\n", field); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func compareRules(before, after Rule) []FieldChange {
+	fields := compareExpr(before.Expr, after.Expr)
+
+	if before.For != after.For {
+		fields = append(fields, FieldChange{Field: "for", Before: before.For, After: after.For})
+	}
+	if before.Interval != after.Interval {
+		fields = append(fields, FieldChange{Field: "interval", Before: before.Interval, After: after.Interval})
+	}
+
+	return append(fields, compareMaps("annotations", before.Annotations, after.Annotations)...)
+}
+
+// compareExpr compares expressions as parsed by prometheus, so only changes of meaning are reported.
+// When only numbers changed, each one is reported as a threshold
+func compareExpr(before, after string) []FieldChange {
+	if before == after {
+		return nil
+	}
+
+	beforeExpr, errBefore := parser.ParseExpr(before)
+	afterExpr, errAfter := parser.ParseExpr(after)
+	if errBefore != nil || errAfter != nil {
+		return []FieldChange{{Field: "expr", Before: before, After: after}}
+	}
+	if beforeExpr.String() == afterExpr.String() {
+		return nil
+	}
+
+	beforeNumbers := replaceNumbers(beforeExpr)
+	afterNumbers := replaceNumbers(afterExpr)
+	if beforeExpr.String() != afterExpr.String() || len(beforeNumbers) != len(afterNumbers) {
+		return []FieldChange{{Field: "expr", Before: before, After: after}}
+	}
+
+	// the same threshold is often repeated in the conditions of an alert, it is reported once
+	var fields []FieldChange
+	seen := map[[2]float64]bool{}
+	for i := range beforeNumbers {
+		pair := [2]float64{beforeNumbers[i], afterNumbers[i]}
+		if pair[0] == pair[1] || seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		fields = append(fields, FieldChange{
+			Field:     "threshold",
+			Before:    strconv.FormatFloat(pair[0], 'g', -1, 64),
+			After:     strconv.FormatFloat(pair[1], 'g', -1, 64),
+			Threshold: true,
+		})
+	}
+
+	return fields
+}
+
+// replaceNumbers returns the numbers of an expression, in the order they are written,
+// replacing them by zero so expressions can be compared without them
+func replaceNumbers(expr parser.Expr) []float64 {
+	var numbers []float64
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if number, ok := node.(*parser.NumberLiteral); ok {
+			numbers = append(numbers, number.Val)
+			number.Val = 0
+		}
+		return nil
+	})
+
+	return numbers
+}
+
+func compareMaps(field string, before, after map[string]string) []FieldChange {
+	var fields []FieldChange
+
+	for _, key := range sortedKeys(after) {
+		if before[key] != after[key] {
+			fields = append(fields, FieldChange{Field: field + "." + key, Before: before[key], After: after[key]})
+		}
+	}
+	for _, key := range sortedKeys(before) {
+		if _, ok := after[key]; !ok {
+			fields = append(fields, FieldChange{Field: field + "." + key, Before: before[key]})
+		}
+	}
+
+	return fields
+}
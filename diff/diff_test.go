@@ -0,0 +1,130 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v3"
+)
+
+var rulesFile = []byte(`
+groups:
+  - name: slo:my-service:short
+    interval: 30s
+    rules:
+      - record: slo:service_errors_total:ratio_rate_5m
+        expr: sum(rate(errors[5m])) / sum(rate(total[5m]))
+        labels:
+          service: my-service
+  - name: slo:my-service:alert
+    rules:
+      - alert: slo:my-service.errors.page
+        expr: slo:service_errors_total:ratio_rate_1h{service="my-service"} > (14.4 * 0.001)
+        for: 2m
+        labels:
+          severity: page
+        annotations:
+          summary: my-service is burning its error budget
+`)
+
+var manifests = []byte(`
+apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: slis-my-service
+spec:
+  groups:
+    - name: slo:my-service:short
+      interval: 30s
+      rules:
+        - record: slo:service_errors_total:ratio_rate_5m
+          expr: sum(rate(errors[5m]))/sum(rate(total[5m]))
+          labels:
+            service: my-service
+---
+apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: slos-alerts-my-service
+spec:
+  groups:
+    - name: slo:my-service:alert
+      rules:
+        - alert: slo:my-service.errors.page
+          expr: slo:service_errors_total:ratio_rate_1h{service="my-service"} > (14.4 * 0.005)
+          for: 120s
+          labels:
+            severity: page
+        - alert: slo:my-service.errors.ticket
+          expr: slo:service_errors_total:ratio_rate_1d{service="my-service"} > (3 * 0.005)
+          labels:
+            severity: ticket
+`)
+
+func TestLoad(t *testing.T) {
+	rules, err := Load(rulesFile, "rules.yml")
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+	assert.Equal(t, `slo:my-service:short record slo:service_errors_total:ratio_rate_5m{service="my-service"}`, rules[0].Key())
+	assert.Equal(t, "30s", rules[0].Interval)
+	assert.Equal(t, `slo:my-service:alert alert slo:my-service.errors.page{severity="page"}`, rules[1].Key())
+	assert.Equal(t, "2m", rules[1].For)
+
+	rules, err = Load(manifests, "manifests.yml")
+	assert.NoError(t, err)
+	assert.Len(t, rules, 3)
+	assert.Equal(t, "2m", rules[1].For)
+	assert.Equal(t, "slo:my-service.errors.ticket", rules[2].Name())
+
+	_, err = Load([]byte("kind: ConfigMap\n"), "config.yml")
+	assert.EqualError(t, err, "config.yml: kind ConfigMap is not supported, only PrometheusRule")
+}
+
+func TestFromGroups(t *testing.T) {
+	groups := rulefmt.RuleGroups{}
+	assert.NoError(t, yaml.Unmarshal(rulesFile, &groups))
+
+	loaded, err := Load(rulesFile, "rules.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, loaded, FromGroups(groups.Groups))
+}
+
+func TestCompare(t *testing.T) {
+	before, err := Load(rulesFile, "rules.yml")
+	assert.NoError(t, err)
+	after, err := Load(manifests, "manifests.yml")
+	assert.NoError(t, err)
+
+	changes := Compare(before, after)
+	assert.Equal(t, []Change{
+		{Type: Changed, Rule: after[1], Fields: []FieldChange{
+			{Field: "threshold", Before: "0.001", After: "0.005", Threshold: true},
+			{Field: "annotations.summary", Before: "my-service is burning its error budget"},
+		}},
+		{Type: Added, Rule: after[2]},
+	}, changes)
+
+	changes = Compare(after, before)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, Removed, changes[1].Type)
+
+	var out bytes.Buffer
+	assert.NoError(t, Write(&out, Compare(before, after)))
+	assert.Equal(t, `~ slo:my-service:alert alert slo:my-service.errors.page{severity="page"}
+    ! threshold: 0.001 -> 0.005
+    annotations.summary: "my-service is burning its error budget" -> ""
++ slo:my-service:alert alert slo:my-service.errors.ticket{severity="ticket"}
+`, out.String())
+}
+
+func TestCompareExpr(t *testing.T) {
+	assert.Nil(t, compareExpr("sum(rate(a[5m]))", "sum ( rate(a[5m]) )"))
+	assert.Equal(t, []FieldChange{{Field: "expr", Before: "sum(rate(a[5m]))", After: "sum(rate(b[5m]))"}},
+		compareExpr("sum(rate(a[5m]))", "sum(rate(b[5m]))"))
+	assert.Equal(t, []FieldChange{{Field: "expr", Before: "a > 1", After: "a > 1 and b > 1"}},
+		compareExpr("a > 1", "a > 1 and b > 1"))
+	assert.Equal(t, []FieldChange{{Field: "threshold", Before: "14.4", After: "10", Threshold: true}},
+		compareExpr("a > (14.4 * 0.001) and b > (14.4 * 0.001)", "a > (10 * 0.001) and b > (10 * 0.001)"))
+}
@@ -0,0 +1,156 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Rule is a recording or alerting rule, loaded from a rules file, a PrometheusRule manifest or generated
+type Rule struct {
+	Group       string
+	Interval    string // interval of the group, empty when the default is used
+	Record      string
+	Alert       string
+	Expr        string
+	For         string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// Kind returns record or alert
+func (r *Rule) Kind() string {
+	if r.Alert != "" {
+		return "alert"
+	}
+
+	return "record"
+}
+
+// Name returns the metric recorded or the name of the alert
+func (r *Rule) Name() string {
+	if r.Alert != "" {
+		return r.Alert
+	}
+
+	return r.Record
+}
+
+// Key identifies a rule by its group, name and labels, like
+// slo:my-service:alert alert slo:my-service.errors.page{severity="page"}
+func (r *Rule) Key() string {
+	return fmt.Sprintf("%s %s %s%s", r.Group, r.Kind(), r.Name(), labels.FromMap(r.Labels).String())
+}
+
+// group is the format shared by rules files and the spec of PrometheusRule manifests
+type group struct {
+	Name     string `yaml:"name"`
+	Interval string `yaml:"interval"`
+	Rules    []struct {
+		Record      string            `yaml:"record"`
+		Alert       string            `yaml:"alert"`
+		Expr        string            `yaml:"expr"`
+		For         string            `yaml:"for"`
+		Labels      map[string]string `yaml:"labels"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"rules"`
+}
+
+type document struct {
+	Kind   string  `yaml:"kind"`
+	Groups []group `yaml:"groups"`
+	Spec   struct {
+		Groups []group `yaml:"groups"`
+	} `yaml:"spec"`
+}
+
+// Load reads the rules of a rules file or of a YAML stream of PrometheusRule manifests
+func Load(content []byte, file string) ([]Rule, error) {
+	var rules []Rule
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		doc := document{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			return rules, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		groups := doc.Groups
+		if doc.Kind != "" {
+			if doc.Kind != "PrometheusRule" {
+				return nil, fmt.Errorf("%s: kind %s is not supported, only PrometheusRule", file, doc.Kind)
+			}
+			groups = doc.Spec.Groups
+		}
+
+		for _, g := range groups {
+			for _, r := range g.Rules {
+				rules = append(rules, Rule{
+					Group:       g.Name,
+					Interval:    normalizeDuration(g.Interval),
+					Record:      r.Record,
+					Alert:       r.Alert,
+					Expr:        r.Expr,
+					For:         normalizeDuration(r.For),
+					Labels:      r.Labels,
+					Annotations: r.Annotations,
+				})
+			}
+		}
+	}
+}
+
+// FromGroups returns the rules of groups generated
+func FromGroups(groups []rulefmt.RuleGroup) []Rule {
+	var rules []Rule
+
+	for _, g := range groups {
+		for _, r := range g.Rules {
+			rules = append(rules, Rule{
+				Group:       g.Name,
+				Interval:    normalizeDuration(g.Interval.String()),
+				Record:      r.Record.Value,
+				Alert:       r.Alert.Value,
+				Expr:        r.Expr.Value,
+				For:         normalizeDuration(r.For.String()),
+				Labels:      r.Labels,
+				Annotations: r.Annotations,
+			})
+		}
+	}
+
+	return rules
+}
+
+// normalizeDuration writes durations the same way, like 60s as 1m, empty when zero
+func normalizeDuration(value string) string {
+	duration, err := model.ParseDuration(value)
+	if err != nil {
+		return value
+	}
+	if duration == 0 {
+		return ""
+	}
+
+	return duration.String()
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -4,11 +4,13 @@ go 1.12
 
 require (
 	github.com/ghodss/yaml v1.0.0
+	github.com/go-kit/log v0.1.0
 	github.com/hashicorp/go-msgpack v0.5.4 // indirect
 	github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring v0.50.0
 	github.com/prometheus/common v0.30.0
 	github.com/prometheus/prometheus v1.8.2-0.20210914090109-37468d88dce8
 	github.com/stretchr/testify v1.7.0
+	gopkg.in/yaml.v2 v2.4.0
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 	k8s.io/apimachinery v0.22.2
 	k8s.io/client-go v0.22.2 // indirect
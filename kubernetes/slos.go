@@ -14,10 +14,20 @@ type Opts struct {
 	DisableTicket bool
 }
 
-func GenerateManifests(opt Opts) []monitoringv1.PrometheusRule {
+func GenerateManifests(opt Opts) ([]monitoringv1.PrometheusRule, error) {
 	rules := []monitoringv1.PrometheusRule{}
 
-	groups := opt.SLO.GenerateGroupRules(opt.Class, opt.DisableTicket)
+	groups, err := opt.SLO.GenerateGroupRules(opt.Class, opt.DisableTicket)
+	if err != nil {
+		return nil, err
+	}
+	budgetGroup, err := opt.SLO.GenerateBudgetGroup(opt.Class, opt.DisableTicket)
+	if err != nil {
+		return nil, err
+	}
+	if len(budgetGroup.Rules) > 0 {
+		groups = append(groups, budgetGroup)
+	}
 	if len(groups) > 0 {
 		rules = append(rules, monitoringv1.PrometheusRule{
 			TypeMeta: metav1.TypeMeta{
@@ -33,7 +43,10 @@ func GenerateManifests(opt Opts) []monitoringv1.PrometheusRule {
 		})
 	}
 
-	alertRules := opt.SLO.GenerateAlertRules(opt.Class, opt.DisableTicket)
+	alertRules, err := opt.SLO.GenerateAlertRules(opt.Class, opt.DisableTicket)
+	if err != nil {
+		return nil, err
+	}
 	if len(alertRules) > 0 {
 		rules = append(rules, monitoringv1.PrometheusRule{
 			TypeMeta: metav1.TypeMeta{
@@ -55,7 +68,7 @@ func GenerateManifests(opt Opts) []monitoringv1.PrometheusRule {
 
 	}
 
-	return rules
+	return rules, nil
 }
 
 func kubernetizeRuleGroups(groups []rulefmt.RuleGroup) []monitoringv1.RuleGroup {
@@ -12,7 +12,7 @@ import (
 )
 
 func TestGenerateManifests(t *testing.T) {
-	manifests := GenerateManifests(Opts{
+	manifests, err := GenerateManifests(Opts{
 		SLO: slo.SLO{
 			Name: "my-team.my-service.payment",
 			Objectives: slo.Objectives{
@@ -44,6 +44,7 @@ func TestGenerateManifests(t *testing.T) {
 		},
 	})
 
+	assert.NoError(t, err)
 	assert.Len(t, manifests, 2)
 	assert.Equal(t, v1.ObjectMeta{
 		Name: "slis-my-team.my-service.payment",
@@ -136,6 +137,12 @@ func TestGenerateManifests(t *testing.T) {
 		},
 	}, manifests[0].Spec.Groups[0])
 
+	budgetGroup := manifests[0].Spec.Groups[len(manifests[0].Spec.Groups)-1]
+	assert.Equal(t, "slo:my-team.my-service.payment:budget", budgetGroup.Name)
+	assert.Equal(t, "5m", budgetGroup.Interval)
+	assert.Equal(t, "slo:sli_error:weighted_rate_1d", budgetGroup.Rules[0].Record)
+	assert.Equal(t, "slo:sli_error:ratio_rate_30d", budgetGroup.Rules[1].Record)
+
 	assert.Equal(t, v1.ObjectMeta{
 		Name: "slos-alerts-my-team.my-service.payment",
 	}, manifests[1].ObjectMeta)
@@ -3,156 +3,92 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"strings"
-
-	ghodssYaml "github.com/ghodss/yaml"
-	"github.com/globocom/slo-generator/kubernetes"
-	"github.com/globocom/slo-generator/slo"
-	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
-	"github.com/prometheus/prometheus/pkg/rulefmt"
-	yaml "gopkg.in/yaml.v3"
 )
 
-func main() {
-	var (
-		sloPath       = ""
-		classesPath   = ""
-		ruleOutput    = ""
-		k8sLabels     = ""
-		disableTicket = false
-		k8s           = false
-	)
-	flag.StringVar(&sloPath, "slo.path", "", "A YML file describing SLOs")
-	flag.StringVar(&classesPath, "classes.path", "", "A YML file describing SLOs classes (optional)")
-	flag.StringVar(&ruleOutput, "rule.output", "", "Output to describe a prometheus rules")
-	flag.BoolVar(&disableTicket, "disable.ticket", false, "Disable generation of alerts of kind ticket")
-	flag.BoolVar(&k8s, "kubernetes", false, "Generates prometheus-operator YAML")
-	flag.StringVar(&k8sLabels, "kubernetes-labels", "", "Add some labels in generated resource")
-
-	flag.Parse()
-
-	if sloPath == "" {
-		log.Fatal("slo.path is a required param")
-	}
-
-	var output io.Writer
-	if ruleOutput == "" {
-		output = os.Stdout
-	} else {
-		targetFile, err := os.Create(ruleOutput)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer targetFile.Close()
-		output = targetFile
-	}
-
-	f, err := os.Open(sloPath)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	spec := &slo.SLOSpec{}
-	err = yaml.NewDecoder(f).Decode(spec)
-	if err != nil {
-		log.Fatal(err)
-	}
+// exit codes of commands, so CI can tell problems in SLOs from problems running the generator
+const (
+	exitOK       = 0
+	exitProblems = 1 // invalid SLOs, lint warnings or differences with the rules file
+	exitError    = 2 // invalid arguments, files that can not be read or written
+)
 
-	classesDefinition, err := readClassesDefinition(classesPath)
-	if err != nil {
-		log.Fatal(err)
-	}
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
 
-	if len(spec.Classes) > 0 && len(classesDefinition.Classes) > 0 {
-		log.Fatal("you can not define classes in slo and classes files")
-	} else if len(classesDefinition.Classes) > 0 {
-		spec.Classes = classesDefinition.Classes
-	}
+var commands = []command{
+	{name: "generate", summary: "Generate prometheus rules, or prometheus-operator manifests with -kubernetes", run: runGenerate},
+	{name: "validate", summary: "Check SLOs and classes, reporting every problem found", run: runValidate},
+	{name: "lint", summary: "Warn about SLOs not following the practices of the SRE workbook", run: runLint},
+	{name: "explain", summary: "Show the effective objectives, alert thresholds and rules of SLOs", run: runExplain},
+	{name: "diff", summary: "Compare the rules generated with an existing rules file", run: runDiff},
+	{name: "simulate", summary: "Evaluate the error alerts of SLOs during synthetic incidents, without prometheus", run: runSimulate},
+	{name: "backtest", summary: "Evaluate the alerts of SLOs over a TSDB or an OpenMetrics dump, listing when they fired", run: runBacktest},
+}
 
-	ruleGroups := &rulefmt.RuleGroups{
-		Groups: []rulefmt.RuleGroup{},
+func main() {
+	// the first argument is a flag when the generator is used as before commands
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runLegacy(os.Args[1:]))
 	}
 
-	if k8s {
-		labels := map[string]string{}
-		if k8sLabels != "" {
-			labels, err = parseLabels(k8sLabels)
-			if err != nil {
-				log.Fatal(err)
-			}
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			os.Exit(cmd.run(os.Args[2:]))
 		}
-
-		manifests := []monitoringv1.PrometheusRule{}
-		for _, slo := range spec.SLOS {
-			// try to use any slo class found
-			sloClass, err := spec.Classes.FindClass(slo.Class)
-			if err != nil {
-				log.Fatalf("Could not compile SLO: %q, err: %q", slo.Name, err.Error())
-			}
-
-			manifests = append(manifests, kubernetes.GenerateManifests(kubernetes.Opts{
-				SLO:           slo,
-				Class:         sloClass,
-				DisableTicket: disableTicket,
-			})...)
-		}
-
-		for i, manifest := range manifests {
-			if manifest.Labels == nil {
-				manifest.Labels = map[string]string{}
-			}
-			for key, value := range labels {
-				manifest.Labels[key] = value
-			}
-
-			b, err := ghodssYaml.Marshal(manifest)
-
-			if err != nil {
-				log.Fatal(err)
-			}
-			if i > 0 {
-				_, err = output.Write([]byte("---\n"))
-				if err != nil {
-					log.Fatal(err)
-				}
-			}
-			_, err = output.Write(b)
-			if err != nil {
-				log.Fatal(err)
-			}
-		}
-		if ruleOutput != "" {
-			log.Printf("generated a kubernetes manifest record in %q", ruleOutput)
-		}
-		return
 	}
 
-	for _, slo := range spec.SLOS {
-		// try to use any slo class found
-		sloClass, err := spec.Classes.FindClass(slo.Class)
-		if err != nil {
-			log.Fatalf("Could not compile SLO: %q, err: %q", slo.Name, err.Error())
-		}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+	usage()
+	os.Exit(exitError)
+}
 
-		ruleGroups.Groups = append(ruleGroups.Groups, slo.GenerateGroupRules(sloClass, disableTicket)...)
-		ruleGroups.Groups = append(ruleGroups.Groups, rulefmt.RuleGroup{
-			Name:  "slo:" + slo.Name + ":alert",
-			Rules: slo.GenerateAlertRules(sloClass, disableTicket),
-		})
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-9s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintf(os.Stderr, "\nRun %s <command> -h to see the flags of a command.\n", os.Args[0])
+}
 
-	err = yaml.NewEncoder(output).Encode(ruleGroups)
-	if err != nil {
-		log.Fatal(err)
+// runLegacy runs the generator with the flags used before commands, -validate and -explain
+// choose the command, which is generate otherwise
+func runLegacy(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	spec := &specFlags{}
+	spec.register(flags)
+	gen := &generateFlags{}
+	gen.register(flags)
+	validate := flags.Bool("validate", false, "Only validate SLOs and classes, reporting all problems found")
+	explain := flags.Bool("explain", false, "Only show the effective objectives of SLOs and where each one was defined")
+	flags.Usage = func() {
+		usage()
+		fmt.Fprintf(os.Stderr, "\nWithout a command, rules are generated with the flags:\n")
+		flags.PrintDefaults()
 	}
-	if ruleOutput != "" {
-		log.Printf("generated a SLO record in %q", ruleOutput)
+	flags.Parse(args)
+
+	switch {
+	case *validate:
+		return validateSLOs(spec)
+	case *explain:
+		return explainSLOs(spec, nil, gen.disableTicket)
+	default:
+		return generateRules(spec, gen)
 	}
 }
 
+// fail logs why a command failed and returns its exit code
+func fail(code int, err error) int {
+	log.Print(err)
+	return code
+}
+
 func parseLabels(labels string) (map[string]string, error) {
 	result := map[string]string{}
 	for _, part := range strings.Split(labels, ",") {
@@ -166,21 +102,14 @@ func parseLabels(labels string) (map[string]string, error) {
 	return result, nil
 }
 
-// readClassesDefinition read SLO classes from filesystem
-func readClassesDefinition(classesPath string) (*slo.ClassesDefinition, error) {
-	classesDefinition := slo.ClassesDefinition{
-		Classes: []slo.Class{},
-	}
-	if classesPath != "" {
-		f, err := os.Open(classesPath)
-		if err != nil {
-			return nil, err
-		}
-		err = yaml.NewDecoder(f).Decode(&classesDefinition)
-		if err != nil {
-			return nil, err
-		}
-	}
+// stringList is a flag that may be given many times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
 
-	return &classesDefinition, nil
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
@@ -0,0 +1,95 @@
+package methods
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// errorAnnotations returns the summary and the description of an alert of the error budget,
+// $value of the alert is the error ratio of the long window
+func errorAnnotations(opts *AlertErrorOptions, rates []MultiRateWindow) map[string]string {
+	if !opts.Annotate {
+		return map[string]string{}
+	}
+
+	// seconds of the budget window that may fail, divided by the error ratio it is the time to exhaust the budget
+	budget := opts.BudgetWindow.Seconds() * (1 - opts.AvailabilityTarget/100)
+
+	return map[string]string{
+		"summary": fmt.Sprintf("%s is burning its error budget", opts.ServiceName),
+		"description": fmt.Sprintf("{{ $value | humanizePercentage }} of requests of %s are failing, the objective is %s%% of successful requests over %s. %s, at the current rate the error budget is exhausted in %s.",
+			opts.ServiceName, formatNumber(opts.AvailabilityTarget), model.Duration(opts.BudgetWindow), describeRates(rates), exhaustion(budget, "%g")),
+	}
+}
+
+// latencyAnnotations returns the summary and the description of an alert of the latency budget,
+// $value of the alert is the ratio of requests faster than le on the long window
+func latencyAnnotations(opts *AlertLatencyOptions, targets []LatencyTarget, rates []MultiRateWindow) map[string]string {
+	if !opts.Annotate {
+		return map[string]string{}
+	}
+
+	annotations := map[string]string{
+		"summary": fmt.Sprintf("%s is burning its latency budget", opts.ServiceName),
+	}
+
+	if len(targets) == 1 {
+		budget := opts.BudgetWindow.Seconds() * (1 - float64(targets[0].Target)/100)
+		annotations["description"] = fmt.Sprintf("{{ $value | humanizePercentage }} of requests of %s are faster than %s, the objective is %s%% over %s. %s, at the current rate the latency budget is exhausted in %s.",
+			opts.ServiceName, targets[0].LE, formatNumber(float64(targets[0].Target)), model.Duration(opts.BudgetWindow), describeRates(rates), exhaustion(budget, "(1 - %g)"))
+
+		return annotations
+	}
+
+	// the budget is not the same for every target, the time to exhaust it is only given per target
+	objectives := make([]string, 0, len(targets))
+	for _, target := range targets {
+		objectives = append(objectives, fmt.Sprintf("%s%% faster than %s", formatNumber(float64(target.Target)), target.LE))
+	}
+	annotations["description"] = fmt.Sprintf("{{ $value | humanizePercentage }} of requests of %s are faster than {{ $labels.le }}, the objectives are %s over %s. %s.",
+		opts.ServiceName, strings.Join(objectives, " and "), model.Duration(opts.BudgetWindow), describeRates(rates))
+
+	return annotations
+}
+
+// describeRates writes the burn rates and windows of an alert, like:
+// the alert fires burning 14.4x the budget over 1h and 5m, or 6x over 6h and 30m
+func describeRates(rates []MultiRateWindow) string {
+	parts := make([]string, 0, len(rates))
+	for i, rate := range rates {
+		part := fmt.Sprintf("%sx", formatNumber(rate.Multiplier))
+		if i == 0 {
+			part += " the budget"
+		}
+		part += " over " + rate.LongWindow
+		if rate.ShortWindow != "" {
+			part += " and " + rate.ShortWindow
+		}
+		parts = append(parts, part)
+	}
+
+	return "The alert fires burning " + strings.Join(parts, ", or ")
+}
+
+// exhaustion returns the template of the time left until the budget is exhausted, burned is
+// the format of the ratio of the budget burned by second, given $value of the alert.
+// Templates can not divide numbers, so the division is queried to prometheus
+func exhaustion(budget float64, burned string) string {
+	division := formatNumber(budget) + " / " + burned
+
+	return fmt.Sprintf(`{{ with printf "%s" $value | query }}{{ . | first | value | humanizeDuration }}{{ end }}`, division)
+}
+
+// formatNumber writes a number without float rounding noise
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(math.Round(number*1e6)/1e6, 'f', -1, 64)
+}
+
+// simpleRates returns the only window of simple alerts
+func simpleRates(burnRate float64, alertWindow string) []MultiRateWindow {
+	return []MultiRateWindow{{Multiplier: burnRate, LongWindow: alertWindow}}
+}
@@ -1,8 +1,12 @@
 package methods
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
 	"time"
 
+	"github.com/globocom/slo-generator/samples"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/rulefmt"
 )
@@ -22,14 +26,13 @@ var (
 
 type Window struct {
 	Duration     model.Duration       `yaml:"duration"`
-	Consumption  float64              `yaml:"consumption"`
+	Consumption  Percent              `yaml:"consumption"`
 	Notification NotificationSeverity `yaml:"notification"`
 }
 
-type AlertErrorOptions struct {
-	ServiceName        string
-	AvailabilityTarget float64
-	SLOWindow          time.Duration
+// RateOptions choose the windows of alerts and the burn rates of the budget firing on them
+type RateOptions struct {
+	SLOWindow time.Duration
 
 	Windows     []Window
 	ShortWindow bool
@@ -37,26 +40,79 @@ type AlertErrorOptions struct {
 
 	// important for simple algorithm
 	AlertWindow string
-	AlertWait   string
+
+	// Severity of alerts, simple alerts are sent with it (page by default)
+	// and multi-window only generates alerts of it (all by default)
+	Severity NotificationSeverity
+}
+
+type AlertErrorOptions struct {
+	ServiceName        string
+	AvailabilityTarget float64
+	RateOptions
+
+	// important for simple algorithm
+	AlertWait string
+
+	// Annotate generates the summary and description annotations of alerts
+	Annotate bool
+	// BudgetWindow is the window of the budget described by annotations
+	BudgetWindow time.Duration
+
+	// ObjectiveMetrics compares SLIs with the objectives recorded by the budget rules,
+	// slo:objective:availability and slo:objective:latency, instead of constants
+	ObjectiveMetrics bool
+
+	// windows recorded for the SLO, default samples are used when nil
+	Samples *samples.Config
 }
 
 type AlertLatencyOptions struct {
 	ServiceName string
 	Targets     []LatencyTarget
-	SLOWindow   time.Duration
-
-	Windows     []Window
-	ShortWindow bool
-	BurnRate    float64
+	RateOptions
 
 	// important for simple algorithm
-	AlertWindow string
-	AlertWait   string
+	AlertWait string
+
+	// PerTarget generates one alert by latency target, labeled by its le and target,
+	// instead of one alert for all targets
+	PerTarget bool
+
+	// Annotate generates the summary and description annotations of alerts
+	Annotate bool
+	// BudgetWindow is the window of the budget described by annotations
+	BudgetWindow time.Duration
+
+	// ObjectiveMetrics compares SLIs with the objectives recorded by the budget rules,
+	// slo:objective:availability and slo:objective:latency, instead of constants
+	ObjectiveMetrics bool
+
+	// windows recorded for the SLO, default samples are used when nil
+	Samples *samples.Config
+}
+
+// OptionError is returned by an alert method when one of its options is not valid
+type OptionError struct {
+	Option string
+	Err    error
+}
+
+func (e *OptionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *OptionError) Unwrap() error {
+	return e.Err
 }
 
 type AlertMethod interface {
 	AlertForError(*AlertErrorOptions) ([]rulefmt.Rule, error)
 	AlertForLatency(*AlertLatencyOptions) ([]rulefmt.Rule, error)
+
+	// Rates returns the windows alerted for each severity, with the burn rate firing on them,
+	// alerts of both signals are built from them
+	Rates(*RateOptions) map[NotificationSeverity][]MultiRateWindow
 }
 
 var methods = map[string]AlertMethod{}
@@ -70,7 +126,58 @@ func Get(name string) AlertMethod {
 	return methods[name]
 }
 
+// Names returns the names of all registered alert methods, sorted
+func Names() []string {
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
 type LatencyTarget struct {
 	LE     string  `yaml:"le"`
-	Target float64 `yaml:"target"`
+	Target Percent `yaml:"target"`
+}
+
+// latencyTargetGroups returns the targets alerted together by each alert
+func latencyTargetGroups(opts *AlertLatencyOptions) [][]LatencyTarget {
+	if !opts.PerTarget {
+		return [][]LatencyTarget{opts.Targets}
+	}
+
+	groups := make([][]LatencyTarget, 0, len(opts.Targets))
+	for _, target := range opts.Targets {
+		groups = append(groups, []LatencyTarget{target})
+	}
+
+	return groups
+}
+
+// latencyTargetRule labels an alert of a single latency target with its le and target,
+// the threshold is also described in annotations
+func latencyTargetRule(rule rulefmt.Rule, targets []LatencyTarget, perTarget bool) rulefmt.Rule {
+	if !perTarget || len(targets) != 1 {
+		return rule
+	}
+
+	target := strconv.FormatFloat(float64(targets[0].Target), 'f', -1, 64)
+	rule.Labels["le"] = targets[0].LE
+	rule.Labels["target"] = target
+	rule.Annotations["threshold"] = targets[0].LE
+	rule.Annotations["objective"] = fmt.Sprintf("%s%% of requests faster than %s", target, targets[0].LE)
+
+	return rule
+}
+
+// objectiveMetric returns the metric of the objective compared by alerts when objectives are read
+// from metrics, empty when alerts compare constants
+func objectiveMetric(objectiveMetrics bool, metric string) string {
+	if !objectiveMetrics {
+		return ""
+	}
+
+	return metric
 }
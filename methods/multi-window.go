@@ -2,6 +2,7 @@ package methods
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -12,8 +13,8 @@ import (
 
 type MultiWindowAlgorithm struct{}
 
-func (*MultiWindowAlgorithm) AlertForError(opts *AlertErrorOptions) ([]rulefmt.Rule, error) {
-	ratesMap := genMultiRateWindows(opts.SLOWindow, opts.ShortWindow, opts.Windows)
+func (m *MultiWindowAlgorithm) AlertForError(opts *AlertErrorOptions) ([]rulefmt.Rule, error) {
+	ratesMap := m.Rates(&opts.RateOptions)
 	rules := []rulefmt.Rule{}
 
 	for _, severity := range Severities {
@@ -27,8 +28,10 @@ func (*MultiWindowAlgorithm) AlertForError(opts *AlertErrorOptions) ([]rulefmt.R
 				Metric: "slo:service_errors_total",
 				Labels: labels.New(labels.Label{Name: "service", Value: opts.ServiceName}),
 				Value:  1 - opts.AvailabilityTarget/100,
+
+				ObjectiveMetric: objectiveMetric(opts.ObjectiveMetrics, "slo:objective:availability"),
 			}),
-			Annotations: map[string]string{},
+			Annotations: errorAnnotations(opts, ratesMap[severity]),
 			Labels: map[string]string{
 				"severity": string(severity),
 				"signal":   "error",
@@ -38,38 +41,61 @@ func (*MultiWindowAlgorithm) AlertForError(opts *AlertErrorOptions) ([]rulefmt.R
 	return rules, nil
 }
 
-func (*MultiWindowAlgorithm) AlertForLatency(opts *AlertLatencyOptions) ([]rulefmt.Rule, error) {
-	ratesMap := genMultiRateWindows(opts.SLOWindow, opts.ShortWindow, opts.Windows)
+func (m *MultiWindowAlgorithm) AlertForLatency(opts *AlertLatencyOptions) ([]rulefmt.Rule, error) {
+	ratesMap := m.Rates(&opts.RateOptions)
 	rules := []rulefmt.Rule{}
 
 	for _, severity := range Severities {
 		if _, ok := ratesMap[severity]; !ok {
 			continue
 		}
-		rules = append(rules, rulefmt.Rule{
-			Alert: "slo:" + opts.ServiceName + ".latency." + string(severity),
-			Expr: multiBurnRateLatency(MultiRateLatencyOpts{
-				Rates:   ratesMap[severity],
-				Metric:  "slo:service_latency",
-				Label:   labels.Label{Name: "service", Value: opts.ServiceName},
-				Buckets: opts.Targets,
-			}),
-			Annotations: map[string]string{},
-			Labels: map[string]string{
-				"severity": string(severity),
-				"signal":   "latency",
-			},
-		})
+		for _, targets := range latencyTargetGroups(opts) {
+			rules = append(rules, latencyTargetRule(rulefmt.Rule{
+				Alert: "slo:" + opts.ServiceName + ".latency." + string(severity),
+				Expr: multiBurnRateLatency(MultiRateLatencyOpts{
+					Rates:   ratesMap[severity],
+					Metric:  "slo:service_latency",
+					Label:   labels.Label{Name: "service", Value: opts.ServiceName},
+					Buckets: targets,
+
+					ObjectiveMetric: objectiveMetric(opts.ObjectiveMetrics, "slo:objective:latency"),
+				}),
+				Annotations: latencyAnnotations(opts, targets, ratesMap[severity]),
+				Labels: map[string]string{
+					"severity": string(severity),
+					"signal":   "latency",
+				},
+			}, targets, opts.PerTarget))
+		}
 	}
 
 	return rules, nil
 }
 
+// Rates returns the windows of the SRE workbook, or the windows given, of every severity
+// or only of the severity given
+func (*MultiWindowAlgorithm) Rates(opts *RateOptions) map[NotificationSeverity][]MultiRateWindow {
+	ratesMap := genMultiRateWindows(opts.SLOWindow, opts.ShortWindow, opts.Windows)
+	if opts.Severity == "" {
+		return ratesMap
+	}
+
+	rates, ok := ratesMap[opts.Severity]
+	if !ok {
+		return map[NotificationSeverity][]MultiRateWindow{}
+	}
+
+	return map[NotificationSeverity][]MultiRateWindow{opts.Severity: rates}
+}
+
 type MultiRateErrorOpts struct {
 	Rates  []MultiRateWindow
 	Metric string
 	Labels labels.Labels
 	Value  float64
+
+	// ObjectiveMetric is compared instead of Value when set, like slo:objective:availability
+	ObjectiveMetric string
 }
 
 type MultiRateLatencyOpts struct {
@@ -77,6 +103,9 @@ type MultiRateLatencyOpts struct {
 	Metric  string
 	Label   labels.Label
 	Buckets []LatencyTarget
+
+	// ObjectiveMetric is compared instead of the targets of Buckets when set, like slo:objective:latency
+	ObjectiveMetric string
 }
 
 type MultiRateWindow struct {
@@ -112,6 +141,26 @@ var multiRateWindows = map[NotificationSeverity][]MultiRateWindow{
 	},
 }
 
+// DefaultWindows returns the windows alerted when none is given, with the error
+// budget each one consumes on the SLO window
+func DefaultWindows(SLOWindow time.Duration) []Window {
+	windows := []Window{}
+
+	for _, severity := range Severities {
+		for _, rate := range multiRateWindows[severity] {
+			duration, _ := model.ParseDuration(rate.LongWindow)
+			consumption := rate.Multiplier * float64(duration) / float64(SLOWindow) * 100
+			windows = append(windows, Window{
+				Duration:     duration,
+				Consumption:  Percent(math.Round(consumption*1e6) / 1e6),
+				Notification: severity,
+			})
+		}
+	}
+
+	return windows
+}
+
 func genMultiRateWindows(SLOWindow time.Duration, shortWindow bool, windows []Window) map[NotificationSeverity][]MultiRateWindow {
 	if len(windows) == 0 {
 		// Use Default multiRateWindows from SRE Book
@@ -124,7 +173,7 @@ func genMultiRateWindows(SLOWindow time.Duration, shortWindow bool, windows []Wi
 	for _, w := range windows {
 		t := float64(time.Duration(w.Duration) / time.Hour)
 
-		burnRate := (w.Consumption / 100) / (t / wHours)
+		burnRate := w.Consumption.Ratio() / (t / wHours)
 		m := MultiRateWindow{
 			Multiplier: burnRate,
 			LongWindow: w.Duration.String(),
@@ -146,9 +195,14 @@ func multiBurnRate(opts MultiRateErrorOpts) string {
 	conditions := []string{}
 
 	for _, window := range multiRateWindow {
-		condition := fmt.Sprintf(`%s:ratio_rate_%s%s > (%g * %.3g)`, opts.Metric, window.LongWindow, opts.Labels.String(), window.Multiplier, opts.Value)
+		threshold := fmt.Sprintf("(%g * %.3g)", window.Multiplier, opts.Value)
+		if opts.ObjectiveMetric != "" {
+			threshold = fmt.Sprintf("on(service) group_left() (%g * (1 - %s%s))", window.Multiplier, opts.ObjectiveMetric, opts.Labels.String())
+		}
+
+		condition := fmt.Sprintf(`%s:ratio_rate_%s%s > %s`, opts.Metric, window.LongWindow, opts.Labels.String(), threshold)
 		if window.ShortWindow != "" {
-			condition = fmt.Sprintf(`(%s and %s:ratio_rate_%s%s > (%g * %.3g))`, condition, opts.Metric, window.ShortWindow, opts.Labels.String(), window.Multiplier, opts.Value)
+			condition = fmt.Sprintf(`(%s and %s:ratio_rate_%s%s > %s)`, condition, opts.Metric, window.ShortWindow, opts.Labels.String(), threshold)
 		}
 
 		conditions = append(conditions, condition)
@@ -167,12 +221,15 @@ func multiBurnRateLatency(opts MultiRateLatencyOpts) string {
 
 	for _, bucket := range opts.Buckets {
 		for _, window := range multiRateWindow {
-			value := (1 - ((100 - bucket.Target) / 100 * window.Multiplier))
 			lbs := labels.New(opts.Label, labels.Label{Name: "le", Value: bucket.LE})
+			threshold := fmt.Sprintf("%.3g", 1-((100-float64(bucket.Target))/100*window.Multiplier))
+			if opts.ObjectiveMetric != "" {
+				threshold = fmt.Sprintf("on(le, service) group_left() (1 - %g * (1 - %s%s))", window.Multiplier, opts.ObjectiveMetric, lbs.String())
+			}
 
-			condition := fmt.Sprintf(`%s:ratio_rate_%s%s < %.3g`, opts.Metric, window.LongWindow, lbs.String(), value)
+			condition := fmt.Sprintf(`%s:ratio_rate_%s%s < %s`, opts.Metric, window.LongWindow, lbs.String(), threshold)
 			if window.ShortWindow != "" {
-				condition = fmt.Sprintf(`(%s and %s:ratio_rate_%s%s < %.3g)`, condition, opts.Metric, window.ShortWindow, lbs.String(), value)
+				condition = fmt.Sprintf(`(%s and %s:ratio_rate_%s%s < %s)`, condition, opts.Metric, window.ShortWindow, lbs.String(), threshold)
 			}
 
 			conditions = append(conditions, condition)
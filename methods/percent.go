@@ -0,0 +1,64 @@
+package methods
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Percent is a value between 0 and 100, it may be written in YAML as:
+// a number (99.9), a percentage string (99.9%) or an explicit ratio ({ratio: 0.999}).
+// Numbers between 0 and 1 are rejected, since they could be both a ratio and a percentage.
+type Percent float64
+
+func (p *Percent) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		if strings.HasSuffix(value.Value, "%") {
+			number, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value.Value, "%")), 64)
+			if err != nil {
+				return fmt.Errorf("line %d: %q is not a valid percentage", value.Line, value.Value)
+			}
+			*p = Percent(number)
+			return nil
+		}
+
+		var number float64
+		if err := value.Decode(&number); err != nil {
+			return fmt.Errorf("line %d: %q is not a valid percentage, use a number (2), a percentage (2%%) or a ratio ({ratio: 0.02})", value.Line, value.Value)
+		}
+		if number > 0 && number < 1 {
+			return fmt.Errorf("line %d: %s is ambiguous, write it as a percentage (%g%% or %s%%) or as a ratio ({ratio: %s})", value.Line, value.Value, number*100, value.Value, value.Value)
+		}
+		*p = Percent(number)
+		return nil
+
+	case yaml.MappingNode:
+		ratio := struct {
+			Ratio *float64 `yaml:"ratio"`
+		}{}
+		if len(value.Content) != 2 || value.Content[0].Value != "ratio" {
+			return fmt.Errorf("line %d: a percentage written as mapping must only have the ratio field", value.Line)
+		}
+		if err := value.Decode(&ratio); err != nil {
+			return err
+		}
+		if ratio.Ratio == nil {
+			return fmt.Errorf("line %d: ratio is required", value.Line)
+		}
+		if *ratio.Ratio < 0 || *ratio.Ratio > 1 {
+			return fmt.Errorf("line %d: ratio must be between 0 and 1, got %g", value.Line, *ratio.Ratio)
+		}
+		*p = Percent(*ratio.Ratio * 100)
+		return nil
+	}
+
+	return fmt.Errorf("line %d: expected a percentage, got %s", value.Line, value.Tag)
+}
+
+// Ratio returns the percentage as a ratio between 0 and 1
+func (p Percent) Ratio() float64 {
+	return float64(p) / 100
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"strings"
 
-	samples "github.com/globocom/slo-generator/samples"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/pkg/rulefmt"
@@ -12,37 +11,34 @@ import (
 
 type SimpleAlgorithm struct{}
 
-func (*SimpleAlgorithm) AlertForError(opts *AlertErrorOptions) ([]rulefmt.Rule, error) {
-	var (
-		burnRate float64 = 1
-		waitFor  model.Duration
-	)
-
-	if opts.BurnRate > 0 {
-		burnRate = opts.BurnRate
-	}
+func (s *SimpleAlgorithm) AlertForError(opts *AlertErrorOptions) ([]rulefmt.Rule, error) {
+	var waitFor model.Duration
 
-	if err := samples.ValidateSample(opts.AlertWindow); err != nil {
-		return nil, err
+	if err := opts.Samples.ValidateSample(opts.AlertWindow); err != nil {
+		return nil, &OptionError{Option: "alertWindow", Err: err}
 	}
 	if opts.AlertWait != "" {
 		var err error
 		waitFor, err = model.ParseDuration(opts.AlertWait)
 		if err != nil {
-			return nil, err
+			return nil, &OptionError{Option: "alertWait", Err: err}
 		}
 	}
 
+	severity, rates := s.rate(&opts.RateOptions)
 	ruleLabels := labels.New(labels.Label{Name: "service", Value: opts.ServiceName})
-	errorLimit := 1 - opts.AvailabilityTarget/100
+	threshold := fmt.Sprintf("%.3g * %.3g", rates[0].Multiplier, 1-opts.AvailabilityTarget/100)
+	if metric := objectiveMetric(opts.ObjectiveMetrics, "slo:objective:availability"); metric != "" {
+		threshold = fmt.Sprintf("on(service) group_left() (%.3g * (1 - %s%s))", rates[0].Multiplier, metric, ruleLabels.String())
+	}
 	rules := []rulefmt.Rule{
 		{
-			Alert:       "slo:" + opts.ServiceName + ".errors.page",
-			Expr:        fmt.Sprintf("slo:service_errors_total:ratio_rate_%s%s > %.3g * %.3g", opts.AlertWindow, ruleLabels.String(), burnRate, errorLimit),
+			Alert:       "slo:" + opts.ServiceName + ".errors." + string(severity),
+			Expr:        fmt.Sprintf("slo:service_errors_total:ratio_rate_%s%s > %s", rates[0].LongWindow, ruleLabels.String(), threshold),
 			For:         waitFor,
-			Annotations: map[string]string{},
+			Annotations: errorAnnotations(opts, rates),
 			Labels: map[string]string{
-				"severity": "page",
+				"severity": string(severity),
 				"signal":   "error",
 			},
 		},
@@ -51,51 +47,52 @@ func (*SimpleAlgorithm) AlertForError(opts *AlertErrorOptions) ([]rulefmt.Rule,
 	return rules, nil
 }
 
-func (*SimpleAlgorithm) AlertForLatency(opts *AlertLatencyOptions) ([]rulefmt.Rule, error) {
+func (s *SimpleAlgorithm) AlertForLatency(opts *AlertLatencyOptions) ([]rulefmt.Rule, error) {
 	var waitFor model.Duration
 
-	if err := samples.ValidateSample(opts.AlertWindow); err != nil {
-		return nil, err
+	if err := opts.Samples.ValidateSample(opts.AlertWindow); err != nil {
+		return nil, &OptionError{Option: "alertWindow", Err: err}
 	}
 	if opts.AlertWait != "" {
 		var err error
 		waitFor, err = model.ParseDuration(opts.AlertWait)
 		if err != nil {
-			return nil, err
+			return nil, &OptionError{Option: "alertWait", Err: err}
 		}
 	}
 
-	rules := []rulefmt.Rule{
-		{
-			Alert:       "slo:" + opts.ServiceName + ".latency.page",
-			Expr:        simpleLatency(opts),
+	severity, rates := s.rate(&opts.RateOptions)
+	rules := []rulefmt.Rule{}
+	for _, targets := range latencyTargetGroups(opts) {
+		rules = append(rules, latencyTargetRule(rulefmt.Rule{
+			Alert:       "slo:" + opts.ServiceName + ".latency." + string(severity),
+			Expr:        simpleLatency(opts.ServiceName, rates[0], targets, objectiveMetric(opts.ObjectiveMetrics, "slo:objective:latency")),
 			For:         waitFor,
-			Annotations: map[string]string{},
+			Annotations: latencyAnnotations(opts, targets, rates),
 			Labels: map[string]string{
-				"severity": "page",
+				"severity": string(severity),
 				"signal":   "latency",
 			},
-		},
+		}, targets, opts.PerTarget))
 	}
 
 	return rules, nil
 }
 
-func simpleLatency(opts *AlertLatencyOptions) string {
-	var (
-		conditions []string
-		burnRate   float64 = 1
-	)
-
-	if opts.BurnRate > 0 {
-		burnRate = opts.BurnRate
-	}
+// simpleLatency returns the conditions of the targets, compared with objectiveMetric when it is set
+func simpleLatency(serviceName string, rate MultiRateWindow, targets []LatencyTarget, objectiveMetric string) string {
+	var conditions []string
 
-	for _, target := range opts.Targets {
-		value := 1 - ((100 - target.Target) * 0.01)
+	for _, target := range targets {
+		// the error budget of latency are the requests slower than le
+		latencyLimit := (100 - float64(target.Target)) * 0.01
 
-		lbs := labels.New(labels.Label{Name: "service", Value: opts.ServiceName}, labels.Label{Name: "le", Value: target.LE})
-		condition := fmt.Sprintf(`slo:service_latency:ratio_rate_%s%s < %.3g * %.3g`, opts.AlertWindow, lbs.String(), burnRate, value)
+		lbs := labels.New(labels.Label{Name: "service", Value: serviceName}, labels.Label{Name: "le", Value: target.LE})
+		threshold := fmt.Sprintf("1 - %.3g * %.3g", rate.Multiplier, latencyLimit)
+		if objectiveMetric != "" {
+			threshold = fmt.Sprintf("on(le, service) group_left() (1 - %.3g * (1 - %s%s))", rate.Multiplier, objectiveMetric, lbs.String())
+		}
+		condition := fmt.Sprintf(`slo:service_latency:ratio_rate_%s%s < %s`, rate.LongWindow, lbs.String(), threshold)
 
 		conditions = append(conditions, condition)
 	}
@@ -103,4 +100,31 @@ func simpleLatency(opts *AlertLatencyOptions) string {
 	return strings.Join(conditions, " or ")
 }
 
+// Rates returns the only window of simple alerts, with its severity
+func (s *SimpleAlgorithm) Rates(opts *RateOptions) map[NotificationSeverity][]MultiRateWindow {
+	severity, rates := s.rate(opts)
+	return map[NotificationSeverity][]MultiRateWindow{severity: rates}
+}
+
+func (*SimpleAlgorithm) rate(opts *RateOptions) (NotificationSeverity, []MultiRateWindow) {
+	return simpleSeverity(opts.Severity), simpleRates(simpleBurnRate(opts.BurnRate), opts.AlertWindow)
+}
+
+// simpleBurnRate returns the burn rate of simple alerts, 1 by default
+func simpleBurnRate(burnRate float64) float64 {
+	if burnRate > 0 {
+		return burnRate
+	}
+
+	return 1
+}
+
+func simpleSeverity(severity NotificationSeverity) NotificationSeverity {
+	if severity == "" {
+		return NotificationPageSeverity
+	}
+
+	return severity
+}
+
 var _ = register(&SimpleAlgorithm{}, "simple")
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	ghodssYaml "github.com/ghodss/yaml"
+	"github.com/globocom/slo-generator/kubernetes"
+	"github.com/globocom/slo-generator/slo"
+	"github.com/globocom/slo-generator/unittest"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// classifiedSLO is a SLO with its class, nil when it has none
+type classifiedSLO struct {
+	slo   slo.SLO
+	class *slo.Class
+}
+
+type generateOpts struct {
+	kubernetes       bool
+	kubernetesLabels map[string]string
+	disableTicket    bool
+}
+
+// classify finds the class of every SLO in its specification, keeping the order they were read
+func classify(specs []*slo.SLOSpec) ([]classifiedSLO, error) {
+	slos := []classifiedSLO{}
+	for _, spec := range specs {
+		for _, s := range spec.SLOS {
+			sloClass, err := spec.Classes.FindClass(s.Class)
+			if err != nil {
+				return nil, fmt.Errorf("Could not compile SLO: %q, err: %q", s.Name, err.Error())
+			}
+			slos = append(slos, classifiedSLO{slo: s, class: sloClass})
+		}
+	}
+
+	return slos, nil
+}
+
+// splitOutput returns the SLOs written to each file of the output directory, and the
+// files in the order they are first used. By file, the path of the SLO file is kept
+// inside the directory, by team, SLOs without the team label are written to unowned.yml
+func splitOutput(slos []classifiedSLO, outputDir, outputBy string) (map[string][]classifiedSLO, []string) {
+	files := map[string][]classifiedSLO{}
+	paths := []string{}
+
+	for _, s := range slos {
+		var path string
+		if outputBy == "team" {
+			team := s.slo.Team(s.class)
+			if team == "" {
+				team = "unowned"
+			}
+			path = filepath.Join(outputDir, team+".yml")
+		} else {
+			// the leading slash keeps relative paths, like ../slo.yml, inside the output directory
+			path = filepath.Join(outputDir, filepath.Clean("/"+s.slo.File()))
+		}
+
+		if _, ok := files[path]; !ok {
+			paths = append(paths, path)
+		}
+		files[path] = append(files[path], s)
+	}
+
+	return files, paths
+}
+
+// writeFile writes generated rules to a file, creating its directory
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0644)
+}
+
+// generate writes the prometheus rules, or the prometheus-operator manifests, of SLOs
+func generate(output io.Writer, slos []classifiedSLO, opts generateOpts) error {
+	if opts.kubernetes {
+		return generateManifests(output, slos, opts)
+	}
+
+	ruleGroups := &rulefmt.RuleGroups{
+		Groups: []rulefmt.RuleGroup{},
+	}
+
+	for _, s := range slos {
+		groups, err := sloRuleGroups(s, opts.disableTicket)
+		if err != nil {
+			return err
+		}
+		ruleGroups.Groups = append(ruleGroups.Groups, groups...)
+	}
+
+	return yaml.NewEncoder(output).Encode(ruleGroups)
+}
+
+// sloRuleGroups returns the groups of recording rules, budget rules and alerts of a SLO
+func sloRuleGroups(s classifiedSLO, disableTicket bool) ([]rulefmt.RuleGroup, error) {
+	groupRules, err := s.slo.GenerateGroupRules(s.class, disableTicket)
+	if err != nil {
+		return nil, err
+	}
+	budgetGroup, err := s.slo.GenerateBudgetGroup(s.class, disableTicket)
+	if err != nil {
+		return nil, err
+	}
+	alertRules, err := s.slo.GenerateAlertRules(s.class, disableTicket)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := groupRules
+	if len(budgetGroup.Rules) > 0 {
+		groups = append(groups, budgetGroup)
+	}
+	groups = append(groups, rulefmt.RuleGroup{
+		Name:  "slo:" + s.slo.Name + ":alert",
+		Rules: alertRules,
+	})
+
+	return groups, nil
+}
+
+func generateManifests(output io.Writer, slos []classifiedSLO, opts generateOpts) error {
+	manifests := []monitoringv1.PrometheusRule{}
+	for _, s := range slos {
+		sloManifests, err := kubernetes.GenerateManifests(kubernetes.Opts{
+			SLO:           s.slo,
+			Class:         s.class,
+			DisableTicket: opts.disableTicket,
+		})
+		if err != nil {
+			return err
+		}
+
+		manifests = append(manifests, sloManifests...)
+	}
+
+	for i, manifest := range manifests {
+		if manifest.Labels == nil {
+			manifest.Labels = map[string]string{}
+		}
+		for key, value := range opts.kubernetesLabels {
+			manifest.Labels[key] = value
+		}
+
+		b, err := ghodssYaml.Marshal(manifest)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := output.Write([]byte("---\n")); err != nil {
+				return err
+			}
+		}
+		if _, err := output.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeTests writes the promtool unit tests of the error alerts of SLOs, returning the number of
+// tests. The rules files are referenced relative to the tests file, as promtool reads them. SLOs
+// whose SLI can not be fed by synthetic counters are not tested, with a warning
+func writeTests(path string, slos []classifiedSLO, ruleFiles []string, disableTicket bool) (int, error) {
+	tests := unittest.File{
+		EvaluationInterval: model.Duration(time.Minute),
+		Tests:              []unittest.TestGroup{},
+	}
+
+	testsDir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return 0, err
+	}
+	for _, ruleFile := range ruleFiles {
+		absolute, err := filepath.Abs(ruleFile)
+		if err != nil {
+			return 0, err
+		}
+		relative, err := filepath.Rel(testsDir, absolute)
+		if err != nil {
+			relative = absolute
+		}
+		tests.RuleFiles = append(tests.RuleFiles, relative)
+	}
+
+	for _, s := range slos {
+		groups, err := unittest.Generate(&s.slo, s.class, disableTicket)
+		if err != nil {
+			log.Printf("warning: %s, its alerts are not tested", err)
+			continue
+		}
+		tests.Tests = append(tests.Tests, groups...)
+	}
+
+	if err := os.MkdirAll(testsDir, 0755); err != nil {
+		return 0, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	return len(tests.Tests), yaml.NewEncoder(file).Encode(tests)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/globocom/slo-generator/slo"
+)
+
+func TestSplitOutput(t *testing.T) {
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"search/slo.yml", `
+slos:
+  - name: search-api
+    labels:
+      team: search
+  - name: search-worker
+    class: BATCH
+classes:
+  - name: BATCH
+    labels:
+      team: '{{ .SLO.Name | reReplaceAll "-.*" "" }}'
+`},
+		{"legacy.yml", `
+slos:
+  - name: legacy-api
+`},
+		{"../shared/slo.yml", `
+slos:
+  - name: shared-api
+    labels:
+      team: search
+`},
+	}
+
+	var specs []*slo.SLOSpec
+	for _, file := range files {
+		spec, err := slo.ParseSLOSpec([]byte(file.content), file.name, true)
+		assert.NoError(t, err)
+		specs = append(specs, spec)
+	}
+	slos, err := classify(specs)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		outputBy string
+		paths    []string
+		slos     map[string][]string
+	}{
+		{
+			// relative paths are kept inside the output directory
+			outputBy: "file",
+			paths:    []string{"out/search/slo.yml", "out/legacy.yml", "out/shared/slo.yml"},
+			slos: map[string][]string{
+				"out/search/slo.yml": {"search-api", "search-worker"},
+				"out/legacy.yml":     {"legacy-api"},
+				"out/shared/slo.yml": {"shared-api"},
+			},
+		},
+		{
+			// the team may come from a template of the class, SLOs without team are unowned
+			outputBy: "team",
+			paths:    []string{"out/search.yml", "out/unowned.yml"},
+			slos: map[string][]string{
+				"out/search.yml":  {"search-api", "search-worker", "shared-api"},
+				"out/unowned.yml": {"legacy-api"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		files, paths := splitOutput(slos, "out", test.outputBy)
+		assert.Equal(t, test.paths, paths, test.outputBy)
+
+		names := map[string][]string{}
+		for path, slos := range files {
+			for _, s := range slos {
+				names[path] = append(names[path], s.slo.Name)
+			}
+		}
+		assert.Equal(t, test.slos, names, test.outputBy)
+	}
+}
@@ -5,13 +5,23 @@ import (
 	"strings"
 )
 
-type sample struct {
-	Name     string
-	Interval string
-	Buckets  []string
+// Sample is a group of windows recorded with the same evaluation interval
+type Sample struct {
+	Name     string   `yaml:"name"`
+	Interval string   `yaml:"interval"`
+	Buckets  []string `yaml:"buckets"`
 }
 
-var DefaultSamples = []sample{
+// Config describes which windows are recorded for every SLI
+type Config struct {
+	Groups []Sample `yaml:"groups"`
+
+	// TicketBuckets are only used by alerts of kind ticket,
+	// they are not recorded when tickets are disabled
+	TicketBuckets []string `yaml:"ticketBuckets"`
+}
+
+var DefaultSamples = []Sample{
 	{
 		Name:     "short",
 		Interval: "30s",
@@ -29,10 +39,34 @@ var DefaultSamples = []sample{
 	},
 }
 
-var disabletBucketsForTickets = []string{"3d", "1d", "2h"}
+var DefaultTicketBuckets = []string{"3d", "1d", "2h"}
 
-func IsTicketSample(sample string) bool {
-	for _, bucketSample := range disabletBucketsForTickets {
+// DefaultConfig is used when SLOs and classes do not define their own samples
+var DefaultConfig = &Config{
+	Groups:        DefaultSamples,
+	TicketBuckets: DefaultTicketBuckets,
+}
+
+func (c *Config) orDefault() *Config {
+	if c == nil {
+		return DefaultConfig
+	}
+
+	return c
+}
+
+// Buckets returns all windows recorded, in the order of groups
+func (c *Config) Buckets() []string {
+	buckets := []string{}
+	for _, sample := range c.orDefault().Groups {
+		buckets = append(buckets, sample.Buckets...)
+	}
+
+	return buckets
+}
+
+func (c *Config) IsTicketSample(sample string) bool {
+	for _, bucketSample := range c.orDefault().TicketBuckets {
 		if bucketSample == sample {
 			return true
 		}
@@ -41,16 +75,20 @@ func IsTicketSample(sample string) bool {
 	return false
 }
 
-func ValidateSample(sample string) error {
-	validSamples := []string{}
-	for _, defaultSample := range DefaultSamples {
-		for _, bucket := range defaultSample.Buckets {
-			if bucket == sample {
-				return nil
-			}
-
-			validSamples = append(validSamples, bucket)
+func (c *Config) ValidateSample(sample string) error {
+	validSamples := c.Buckets()
+	for _, bucket := range validSamples {
+		if bucket == sample {
+			return nil
 		}
 	}
 	return fmt.Errorf("Sample %s is not a valid sample, valid samples: %s", sample, strings.Join(validSamples, ","))
 }
+
+func IsTicketSample(sample string) bool {
+	return DefaultConfig.IsTicketSample(sample)
+}
+
+func ValidateSample(sample string) error {
+	return DefaultConfig.ValidateSample(sample)
+}
@@ -0,0 +1,242 @@
+package simulate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Firing is a period during which an alert fired
+type Firing struct {
+	Alert  string
+	Labels labels.Labels
+	Start  time.Time
+	End    time.Time // zero when the alert still fires at the end of the evaluation
+}
+
+// Evaluator evaluates rule groups as prometheus does, each group at its interval, the series
+// recorded are kept in memory and queried with the series of the storage given
+type Evaluator struct {
+	groups   []evalGroup
+	recorded *Storage
+	query    rules.QueryFunc
+	step     time.Duration
+}
+
+type evalGroup struct {
+	name     string
+	interval time.Duration
+	rules    []rules.Rule
+}
+
+// NewEvaluator parses the rules of groups, groups without interval are evaluated at defaultInterval
+func NewEvaluator(groups []rulefmt.RuleGroup, queryable storage.Queryable, defaultInterval time.Duration) (*Evaluator, error) {
+	e := &Evaluator{recorded: NewStorage()}
+
+	for _, g := range groups {
+		group := evalGroup{name: g.Name, interval: time.Duration(g.Interval)}
+		if group.interval == 0 {
+			group.interval = defaultInterval
+		}
+		// groups are evaluated at the multiples of their interval, stepping by the greatest common divisor
+		e.step = gcd(e.step, group.interval)
+
+		for _, r := range g.Rules {
+			expr, err := parser.ParseExpr(r.Expr.Value)
+			if err != nil {
+				return nil, fmt.Errorf("group %s: %w", g.Name, err)
+			}
+			if r.Record.Value != "" {
+				group.rules = append(group.rules, rules.NewRecordingRule(r.Record.Value, expr, labels.FromMap(r.Labels)))
+				continue
+			}
+			group.rules = append(group.rules, rules.NewAlertingRule(
+				r.Alert.Value, expr, time.Duration(r.For),
+				labels.FromMap(r.Labels), labels.FromMap(r.Annotations), nil, "", true, log.NewNopLogger(),
+			))
+		}
+
+		e.groups = append(e.groups, group)
+	}
+
+	engine := promql.NewEngine(promql.EngineOpts{
+		Logger:     log.NewNopLogger(),
+		MaxSamples: 50000000,
+		Timeout:    10 * time.Minute,
+	})
+	e.query = rules.EngineQueryFunc(engine, storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		base, err := queryable.Querier(ctx, mint, maxt)
+		if err != nil {
+			return nil, err
+		}
+		recorded, err := e.recorded.Querier(ctx, mint, maxt)
+		if err != nil {
+			return nil, err
+		}
+
+		return storage.NewMergeQuerier([]storage.Querier{base, recorded}, nil, storage.ChainedSeriesMerge), nil
+	}))
+
+	return e, nil
+}
+
+// Run evaluates the groups from start to end, returning the periods each alert fired, sorted by their start
+func (e *Evaluator) Run(ctx context.Context, start, end time.Time) ([]Firing, error) {
+	var firings []Firing
+	open := map[string]int{} // alerts firing, by their labels, to the index of their firing
+
+	for ts := start; !ts.After(end); ts = ts.Add(e.step) {
+		for _, group := range e.groups {
+			if ts.Sub(start)%group.interval != 0 {
+				continue
+			}
+
+			for _, rule := range group.rules {
+				vector, err := rule.Eval(ctx, ts, e.query, nil)
+				if err != nil {
+					return nil, fmt.Errorf("group %s, rule %s: %w", group.name, rule.Name(), err)
+				}
+
+				alerting, ok := rule.(*rules.AlertingRule)
+				if !ok {
+					for _, sample := range vector {
+						e.recorded.Add(sample.Metric, sample.T, sample.V)
+					}
+					continue
+				}
+
+				firing := map[string]bool{}
+				for _, alert := range alerting.ActiveAlerts() {
+					if alert.State != rules.StateFiring {
+						continue
+					}
+					key := alert.Labels.String()
+					firing[key] = true
+					if _, ok := open[key]; !ok {
+						open[key] = len(firings)
+						firings = append(firings, Firing{Alert: rule.Name(), Labels: alert.Labels, Start: ts})
+					}
+				}
+				for key, i := range open {
+					if firings[i].Alert == rule.Name() && !firing[key] {
+						firings[i].End = ts
+						delete(open, key)
+					}
+				}
+			}
+		}
+	}
+
+	return firings, nil
+}
+
+// Query runs an instant query over the series of the storage and the series recorded
+func (e *Evaluator) Query(ctx context.Context, query string, ts time.Time) (promql.Vector, error) {
+	return e.query(ctx, query, ts)
+}
+
+// RulesFor returns the rules of groups needed by the alerts kept: the alerts and the recording
+// rules they read, directly or through other recording rules. Groups left empty are removed
+func RulesFor(groups []rulefmt.RuleGroup, keep func(alert rulefmt.RuleNode) bool) ([]rulefmt.RuleGroup, error) {
+	needed := map[string]bool{}
+	addNames := func(expr string) error {
+		parsed, err := parser.ParseExpr(expr)
+		if err != nil {
+			return err
+		}
+		parser.Inspect(parsed, func(node parser.Node, _ []parser.Node) error {
+			if selector, ok := node.(*parser.VectorSelector); ok {
+				needed[selector.Name] = true
+			}
+			return nil
+		})
+		return nil
+	}
+
+	for _, g := range groups {
+		for _, r := range g.Rules {
+			if r.Alert.Value != "" && keep(r) {
+				if err := addNames(r.Expr.Value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// recording rules may read other recording rules, until no name is added
+	read := map[string]bool{}
+	for added := true; added; {
+		added = false
+		for _, g := range groups {
+			for _, r := range g.Rules {
+				if r.Record.Value == "" || !needed[r.Record.Value] || read[r.Record.Value] {
+					continue
+				}
+				read[r.Record.Value] = true
+				if err := addNames(r.Expr.Value); err != nil {
+					return nil, err
+				}
+				added = true
+			}
+		}
+	}
+
+	var kept []rulefmt.RuleGroup
+	for _, g := range groups {
+		group := g
+		group.Rules = nil
+		for _, r := range g.Rules {
+			if (r.Alert.Value != "" && keep(r)) || (r.Record.Value != "" && needed[r.Record.Value]) {
+				group.Rules = append(group.Rules, r)
+			}
+		}
+		if len(group.Rules) > 0 {
+			kept = append(kept, group)
+		}
+	}
+
+	return kept, nil
+}
+
+// LongestRange returns the longest range selected by the rules, like 3d for rate(x[3d])
+func LongestRange(groups []rulefmt.RuleGroup) time.Duration {
+	var longest time.Duration
+	for _, g := range groups {
+		for _, r := range g.Rules {
+			expr, err := parser.ParseExpr(r.Expr.Value)
+			if err != nil {
+				continue
+			}
+			parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+				switch n := node.(type) {
+				case *parser.MatrixSelector:
+					if n.Range > longest {
+						longest = n.Range
+					}
+				case *parser.SubqueryExpr:
+					if n.Range > longest {
+						longest = n.Range
+					}
+				}
+				return nil
+			})
+		}
+	}
+
+	return longest
+}
+
+func gcd(a, b time.Duration) time.Duration {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
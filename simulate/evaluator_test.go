@@ -0,0 +1,68 @@
+package simulate
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestRulesFor(t *testing.T) {
+	var groups rulefmt.RuleGroups
+	assert.NoError(t, yaml.Unmarshal([]byte(`
+groups:
+  - name: short
+    rules:
+      - record: errors:rate_5m
+        expr: sum(rate(errors[5m]))
+      - record: requests:rate_5m
+        expr: sum(rate(requests[5m]))
+      - record: latency:rate_5m
+        expr: sum(rate(latency_bucket[5m]))
+      - record: errors:ratio_rate_5m
+        expr: errors:rate_5m / requests:rate_5m
+  - name: latency
+    rules:
+      - alert: latency
+        expr: latency:rate_5m < 0.9
+        labels:
+          signal: latency
+  - name: alerts
+    rules:
+      - alert: errors
+        expr: errors:ratio_rate_5m > 0.01
+        labels:
+          signal: error
+`), &groups))
+
+	kept, err := RulesFor(groups.Groups, func(alert rulefmt.RuleNode) bool {
+		return alert.Labels["signal"] == "error"
+	})
+	assert.NoError(t, err)
+
+	var names []string
+	for _, group := range kept {
+		for _, rule := range group.Rules {
+			names = append(names, group.Name+" "+rule.Record.Value+rule.Alert.Value)
+		}
+	}
+	assert.Equal(t, []string{
+		"short errors:rate_5m",
+		"short requests:rate_5m",
+		"short errors:ratio_rate_5m",
+		"alerts errors",
+	}, names)
+	assert.Equal(t, 5*60e9, float64(LongestRange(kept)))
+}
+
+func TestStorageAddOutOfOrder(t *testing.T) {
+	s := NewStorage()
+	lbls := labels.FromStrings("__name__", "requests")
+	for _, ts := range []int64{1000, 3000, 2000, 3000, 0} {
+		s.Add(lbls, ts, float64(ts))
+	}
+
+	assert.Equal(t, samples{{t: 0, v: 0}, {t: 1000, v: 1000}, {t: 2000, v: 2000}, {t: 3000, v: 3000}}, s.series[lbls.String()].samples)
+}
@@ -0,0 +1,125 @@
+package simulate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/globocom/slo-generator/methods"
+	"github.com/prometheus/common/model"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// shapes of the errors of an incident
+const (
+	Constant = "constant"  // errors from the start of the incident until the end of the simulation
+	Spike    = "spike"     // errors during the incident, then back to the baseline
+	SlowBurn = "slow-burn" // errors growing from the baseline during the incident, then back to the baseline
+)
+
+// defaultAfter is simulated after the end of an incident, so alerts reset
+const defaultAfter = 24 * time.Hour
+
+// Profile is a synthetic incident: the error ratio over time, starting at zero
+type Profile struct {
+	Name     string          `yaml:"name"`
+	Shape    string          `yaml:"shape"`
+	Baseline methods.Percent `yaml:"baseline"` // errors before and after the incident
+	Errors   methods.Percent `yaml:"errors"`   // errors of the incident, reached at its end by slow-burn
+	Duration model.Duration  `yaml:"duration"` // duration of the incident, not used by constant
+	Length   model.Duration  `yaml:"length"`   // time simulated since the start of the incident
+}
+
+type profilesFile struct {
+	Profiles []Profile `yaml:"profiles"`
+}
+
+// ParseProfiles reads the profiles of a YAML file, unknown fields are rejected
+func ParseProfiles(content []byte, file string) ([]Profile, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	parsed := profilesFile{}
+	if err := decoder.Decode(&parsed); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if len(parsed.Profiles) == 0 {
+		return nil, fmt.Errorf("%s: no profile is defined", file)
+	}
+
+	for i := range parsed.Profiles {
+		if err := parsed.Profiles[i].validate(); err != nil {
+			return nil, fmt.Errorf("%s: profile %d: %w", file, i+1, err)
+		}
+	}
+
+	return parsed.Profiles, nil
+}
+
+// validate checks the profile, filling its defaults
+func (p *Profile) validate() error {
+	switch p.Shape {
+	case Constant:
+		if p.Length == 0 {
+			p.Length = model.Duration(defaultAfter)
+		}
+		p.Duration = p.Length
+	case Spike, SlowBurn:
+		if p.Duration <= 0 {
+			return fmt.Errorf("duration of the %s is required", p.Shape)
+		}
+		if p.Length == 0 {
+			p.Length = p.Duration + model.Duration(defaultAfter)
+		}
+	default:
+		return fmt.Errorf("shape %q is not valid, use %s, %s or %s", p.Shape, Constant, Spike, SlowBurn)
+	}
+
+	if p.Errors <= 0 || p.Errors > 100 {
+		return fmt.Errorf("errors must be a percentage above 0 and up to 100, got %s", formatPercent(p.Errors.Ratio()))
+	}
+	if p.Baseline < 0 || p.Baseline >= p.Errors {
+		return fmt.Errorf("baseline must be a percentage below the errors, got %s", formatPercent(p.Baseline.Ratio()))
+	}
+	if p.Length < p.Duration {
+		return fmt.Errorf("length %s is shorter than the duration %s", p.Length, p.Duration)
+	}
+	if p.Name == "" {
+		p.Name = p.String()
+	}
+
+	return nil
+}
+
+// ErrorRatio returns the ratio of errors at a time since the start of the incident
+func (p *Profile) ErrorRatio(at time.Duration) float64 {
+	baseline, errors := p.Baseline.Ratio(), p.Errors.Ratio()
+	duration := time.Duration(p.Duration)
+
+	switch {
+	case at < 0 || (p.Shape != Constant && at >= duration):
+		return baseline
+	case p.Shape == SlowBurn:
+		return baseline + (errors-baseline)*float64(at)/float64(duration)
+	default:
+		return errors
+	}
+}
+
+// String describes the profile, like "spike of 20% errors for 30m"
+func (p *Profile) String() string {
+	errors := formatPercent(p.Errors.Ratio())
+	switch p.Shape {
+	case Constant:
+		return fmt.Sprintf("constant %s errors", errors)
+	case SlowBurn:
+		return fmt.Sprintf("slow burn up to %s errors for %s", errors, p.Duration)
+	default:
+		return fmt.Sprintf("spike of %s errors for %s", errors, p.Duration)
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	return model.Duration(d).String()
+}
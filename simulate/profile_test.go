@@ -0,0 +1,88 @@
+package simulate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProfiles(t *testing.T) {
+	profiles, err := ParseProfiles([]byte(`
+profiles:
+  - name: outage
+    shape: spike
+    errors: 20%
+    duration: 30m
+  - shape: slow-burn
+    baseline: 0.1%
+    errors: 5%
+    duration: 12h
+  - shape: constant
+    errors: 2%
+`), "profiles.yml")
+	assert.NoError(t, err)
+	assert.Len(t, profiles, 3)
+
+	assert.Equal(t, "outage", profiles[0].Name)
+	assert.Equal(t, model.Duration(24*time.Hour+30*time.Minute), profiles[0].Length)
+	assert.Equal(t, "slow burn up to 5% errors for 12h", profiles[1].Name)
+	assert.Equal(t, "constant 2% errors", profiles[2].Name)
+	assert.Equal(t, model.Duration(24*time.Hour), profiles[2].Length)
+	assert.Equal(t, profiles[2].Length, profiles[2].Duration)
+}
+
+func TestParseProfilesInvalid(t *testing.T) {
+	testCases := []struct {
+		content string
+		err     string
+	}{
+		{
+			content: "profiles: []",
+			err:     "profiles.yml: no profile is defined",
+		},
+		{
+			content: "profiles: [{shape: burst, errors: 2%}]",
+			err:     `profiles.yml: profile 1: shape "burst" is not valid, use constant, spike or slow-burn`,
+		},
+		{
+			content: "profiles: [{shape: spike, errors: 2%}]",
+			err:     "profiles.yml: profile 1: duration of the spike is required",
+		},
+		{
+			content: "profiles: [{shape: constant, errors: 2%, baseline: 3%}]",
+			err:     "profiles.yml: profile 1: baseline must be a percentage below the errors, got 3%",
+		},
+		{
+			content: "profiles: [{shape: spike, errors: 2%, duration: 1h, length: 30m}]",
+			err:     "profiles.yml: profile 1: length 30m is shorter than the duration 1h",
+		},
+		{
+			content: "profiles: [{shape: constant, error: 2%}]",
+			err:     "profiles.yml: yaml: unmarshal errors:\n  line 1: field error not found in type simulate.Profile",
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, err := ParseProfiles([]byte(testCase.content), "profiles.yml")
+		assert.EqualError(t, err, testCase.err)
+	}
+}
+
+func TestProfileErrorRatio(t *testing.T) {
+	spike := Profile{Shape: Spike, Baseline: 0.1, Errors: 10, Duration: model.Duration(time.Hour)}
+	assert.Equal(t, 0.001, spike.ErrorRatio(-time.Minute))
+	assert.Equal(t, 0.1, spike.ErrorRatio(0))
+	assert.Equal(t, 0.1, spike.ErrorRatio(59*time.Minute))
+	assert.Equal(t, 0.001, spike.ErrorRatio(time.Hour))
+
+	slowBurn := Profile{Shape: SlowBurn, Errors: 10, Duration: model.Duration(time.Hour)}
+	assert.Equal(t, 0.0, slowBurn.ErrorRatio(0))
+	assert.Equal(t, 0.05, slowBurn.ErrorRatio(30*time.Minute))
+	assert.Equal(t, 0.0, slowBurn.ErrorRatio(time.Hour))
+
+	constant := Profile{Shape: Constant, Errors: 10, Duration: model.Duration(time.Hour)}
+	assert.Equal(t, 0.0, constant.ErrorRatio(-time.Minute))
+	assert.Equal(t, 0.1, constant.ErrorRatio(2*time.Hour))
+}
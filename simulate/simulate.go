@@ -0,0 +1,210 @@
+// Package simulate evaluates the rules generated for a SLO without a prometheus server: synthetic
+// counters of its SLI follow an error profile and the rules run on the promql engine in memory
+package simulate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/globocom/slo-generator/slo"
+	"github.com/globocom/slo-generator/synthetic"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+)
+
+const requestsPerMinute = 1000.0
+
+// Options of a simulation
+type Options struct {
+	DisableTicket      bool
+	EvaluationInterval time.Duration // interval of the groups without one, like the alerts
+	ScrapeInterval     time.Duration // interval of the samples of the synthetic counters
+}
+
+// Result is how the error alerts of a SLO behaved during an incident
+type Result struct {
+	SLO     string
+	Profile *Profile
+	Alerts  []AlertResult
+	Budget  float64 // ratio of the error budget consumed since the start of the incident
+}
+
+// AlertResult is how an alert behaved during an incident
+type AlertResult struct {
+	Alert              string
+	Severity           string
+	Firings            []Firing
+	Detection          time.Duration // from the start of the incident to the first firing
+	Reset              time.Duration // from the end of the incident to the end of the last firing
+	BudgetBeforeFiring float64       // ratio of the error budget consumed from the start of the incident to the first firing
+}
+
+// Fired returns true when the alert fired during the simulation
+func (r *AlertResult) Fired() bool {
+	return len(r.Firings) > 0
+}
+
+// Resolved returns true when the alert fired and stopped before the end of the simulation
+func (r *AlertResult) Resolved() bool {
+	return r.Fired() && !r.Firings[len(r.Firings)-1].End.IsZero()
+}
+
+// Run simulates the error alerts of a SLO during an incident. The counters start before the
+// incident, at its baseline, for the longest window of the rules, so every window is full
+func Run(ctx context.Context, s *slo.SLO, sloClass *slo.Class, profile *Profile, opts Options) (*Result, error) {
+	if s.HasNativeHistogram() {
+		return nil, fmt.Errorf("SLO %q: %w", s.Name, slo.ErrNativeHistogram)
+	}
+	counters, err := synthetic.ErrorCounters(&s.ErrorRateRecord)
+	if err != nil {
+		return nil, fmt.Errorf("SLO %q: %w", s.Name, err)
+	}
+
+	groups, err := s.GenerateGroupRules(sloClass, opts.DisableTicket)
+	if err != nil {
+		return nil, err
+	}
+	// alerts may compare with the objectives recorded by the budget rules
+	budgetGroup, err := s.GenerateBudgetGroup(sloClass, opts.DisableTicket)
+	if err != nil {
+		return nil, err
+	}
+	if len(budgetGroup.Rules) > 0 {
+		groups = append(groups, budgetGroup)
+	}
+	alerts, err := s.GenerateAlertRules(sloClass, opts.DisableTicket)
+	if err != nil {
+		return nil, err
+	}
+	groups = append(groups, rulefmt.RuleGroup{Name: "slo:" + s.Name + ":alert", Rules: alerts})
+
+	isErrorAlert := func(alert rulefmt.RuleNode) bool {
+		return alert.Labels["signal"] == "error"
+	}
+	groups, err = RulesFor(groups, isErrorAlert)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{SLO: s.Name, Profile: profile}
+	for _, alert := range alerts {
+		if isErrorAlert(alert) {
+			result.Alerts = append(result.Alerts, AlertResult{Alert: alert.Alert.Value, Severity: alert.Labels["severity"]})
+		}
+	}
+	if len(result.Alerts) == 0 {
+		return nil, fmt.Errorf("SLO %q: %w", s.Name, errNoErrorAlert)
+	}
+
+	history := LongestRange(groups)
+	start := time.Unix(0, 0).UTC().Add(history + opts.ScrapeInterval)
+	end := start.Add(time.Duration(profile.Length))
+
+	inputs := NewStorage()
+	totals := map[string]float64{}
+	// each sample counts the requests since the previous one, at the error ratio of then
+	for t := start.Add(-history); !t.After(end); t = t.Add(opts.ScrapeInterval) {
+		series, err := counters.Series(profile.ErrorRatio(t.Sub(start)-opts.ScrapeInterval), requestsPerMinute)
+		if err != nil {
+			return nil, fmt.Errorf("SLO %q: %w", s.Name, err)
+		}
+		for _, counter := range series {
+			totals[counter.String()] += counter.PerMinute * opts.ScrapeInterval.Minutes()
+			inputs.Add(counter.Labels, t.UnixNano()/int64(time.Millisecond), totals[counter.String()])
+		}
+	}
+
+	evaluator, err := NewEvaluator(groups, inputs, opts.EvaluationInterval)
+	if err != nil {
+		return nil, err
+	}
+	firings, err := evaluator.Run(ctx, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("SLO %q: %w", s.Name, err)
+	}
+
+	budgetRatio, budgetWindow := s.ErrorBudget(sloClass)
+	consumed := func(to time.Time) float64 {
+		errors := 0.0
+		for t := time.Duration(0); t < to.Sub(start); t += opts.ScrapeInterval {
+			errors += profile.ErrorRatio(t) * float64(opts.ScrapeInterval)
+		}
+		return errors / (budgetRatio * float64(budgetWindow))
+	}
+	result.Budget = consumed(end)
+
+	incidentEnd := start.Add(time.Duration(profile.Duration))
+	for i := range result.Alerts {
+		alert := &result.Alerts[i]
+		for _, firing := range firings {
+			if firing.Alert == alert.Alert {
+				alert.Firings = append(alert.Firings, firing)
+			}
+		}
+		if !alert.Fired() {
+			continue
+		}
+
+		alert.Detection = alert.Firings[0].Start.Sub(start)
+		alert.BudgetBeforeFiring = consumed(alert.Firings[0].Start)
+		if alert.Resolved() {
+			alert.Reset = alert.Firings[len(alert.Firings)-1].End.Sub(incidentEnd)
+		}
+	}
+
+	return result, nil
+}
+
+var errNoErrorAlert = errors.New("no error alert is generated, set errorRateRecord.alertMethod")
+
+// Write writes the results, one line by alert with its detection, the budget consumed
+// before it fired and its reset after the end of the incident
+func Write(w io.Writer, results []*Result) error {
+	for _, result := range results {
+		if _, err := fmt.Fprintf(w, "SLO %q, %s:\n", result.SLO, result.Profile.Name); err != nil {
+			return err
+		}
+		for _, alert := range result.Alerts {
+			if _, err := fmt.Fprintf(w, "  %s: %s\n", alert.Severity, describe(&alert, result.Profile)); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "  budget: %s consumed in %s\n", formatPercent(result.Budget), result.Profile.Length); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func describe(alert *AlertResult, profile *Profile) string {
+	if !alert.Fired() {
+		return "not fired"
+	}
+
+	text := fmt.Sprintf("fired after %s, %s of the budget consumed before", formatDuration(alert.Detection), formatPercent(alert.BudgetBeforeFiring))
+	if count := len(alert.Firings); count > 1 {
+		text += fmt.Sprintf(", fired %d times", count)
+	}
+
+	switch {
+	case !alert.Resolved():
+		text += ", still firing at the end"
+	case profile.Shape == Constant:
+		text += fmt.Sprintf(", reset after %s", formatDuration(alert.Firings[len(alert.Firings)-1].End.Sub(alert.Firings[0].Start)))
+	case alert.Reset < 0:
+		text += fmt.Sprintf(", reset %s before the end of the incident", formatDuration(-alert.Reset))
+	default:
+		text += fmt.Sprintf(", reset %s after the end of the incident", formatDuration(alert.Reset))
+	}
+
+	return text
+}
+
+func formatPercent(ratio float64) string {
+	return strconv.FormatFloat(math.Round(ratio*100*1e4)/1e4, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,103 @@
+package simulate
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/globocom/slo-generator/slo"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var simpleSLO = []byte(`
+slos:
+  - name: api
+    objectives:
+      availability: 99
+    errorRateRecord:
+      alertMethod: simple
+      alertWindow: 1h
+      burnRate: 10
+      severity: page
+      badEvents: 'http_requests_total{job="api", code=~"5.."}'
+      totalEvents: 'http_requests_total{job="api"}'
+`)
+
+func TestRun(t *testing.T) {
+	spec, err := slo.ParseSLOSpec(simpleSLO, "slo.yml", true)
+	assert.NoError(t, err)
+
+	profiles, err := ParseProfiles([]byte(`
+profiles:
+  - shape: spike
+    errors: 50%
+    duration: 30m
+    length: 3h
+`), "profiles.yml")
+	assert.NoError(t, err)
+
+	result, err := Run(context.Background(), &spec.SLOS[0], nil, &profiles[0], Options{
+		EvaluationInterval: time.Minute,
+		ScrapeInterval:     time.Minute,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, result.Alerts, 1)
+
+	// the error ratio of the last hour is above 10% after 12m of 50% errors,
+	// and back to 10% when only 12m of errors are left in the hour, 78m after the start
+	alert := result.Alerts[0]
+	assert.Equal(t, "slo:api.errors.page", alert.Alert)
+	assert.Equal(t, "page", alert.Severity)
+	assert.Len(t, alert.Firings, 1)
+	assert.Equal(t, 13*time.Minute, alert.Detection)
+	assert.InDelta(t, 0.5*13/(0.01*30*24*60), alert.BudgetBeforeFiring, 1e-9)
+	assert.True(t, alert.Resolved())
+	assert.Equal(t, 48*time.Minute, alert.Reset)
+	assert.InDelta(t, 0.5*30/(0.01*30*24*60), result.Budget, 1e-9)
+
+	var output bytes.Buffer
+	assert.NoError(t, Write(&output, []*Result{result}))
+	assert.Equal(t, `SLO "api", spike of 50% errors for 30m:
+  page: fired after 13m, 1.5046% of the budget consumed before, reset 48m after the end of the incident
+  budget: 3.4722% consumed in 3h
+`, output.String())
+}
+
+func TestRunWithObjectiveMetrics(t *testing.T) {
+	spec, err := slo.ParseSLOSpec(bytes.Replace(simpleSLO, []byte("    objectives:"), []byte("    objectiveMetrics: true\n    objectives:"), 1), "slo.yml", true)
+	assert.NoError(t, err)
+
+	profile := &Profile{Shape: Spike, Errors: 50, Duration: model.Duration(30 * time.Minute), Length: model.Duration(3 * time.Hour)}
+	assert.NoError(t, profile.validate())
+
+	// the alert compares with slo:objective:availability, recorded by the budget group
+	result, err := Run(context.Background(), &spec.SLOS[0], nil, profile, Options{
+		EvaluationInterval: time.Minute,
+		ScrapeInterval:     time.Minute,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, result.Alerts, 1)
+	assert.Len(t, result.Alerts[0].Firings, 1)
+	assert.Equal(t, 13*time.Minute, result.Alerts[0].Detection)
+	assert.Equal(t, 48*time.Minute, result.Alerts[0].Reset)
+}
+
+func TestRunWithoutErrorAlerts(t *testing.T) {
+	spec, err := slo.ParseSLOSpec([]byte(`
+slos:
+  - name: api
+    objectives:
+      availability: 99
+    errorRateRecord:
+      expr: sum(rate(errors[$window])) / sum(rate(requests[$window]))
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	profile := &Profile{Shape: Constant, Errors: 1}
+	assert.NoError(t, profile.validate())
+
+	_, err = Run(context.Background(), &spec.SLOS[0], nil, profile, Options{EvaluationInterval: time.Minute, ScrapeInterval: time.Minute})
+	assert.EqualError(t, err, `SLO "api": no error alert is generated, set errorRateRecord.alertMethod`)
+}
@@ -0,0 +1,151 @@
+package simulate
+
+import (
+	"context"
+	"sort"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+)
+
+// Storage keeps series in memory
+type Storage struct {
+	series map[string]*memSeries
+}
+
+type memSeries struct {
+	labels  labels.Labels
+	samples samples
+}
+
+// NewStorage returns an empty storage
+func NewStorage() *Storage {
+	return &Storage{series: map[string]*memSeries{}}
+}
+
+// Add adds a sample to a series, a sample at the time of another one of the series is ignored.
+// Samples are usually added in time order, others are inserted at their place
+func (s *Storage) Add(lbls labels.Labels, t int64, v float64) {
+	key := lbls.String()
+	series, ok := s.series[key]
+	if !ok {
+		series = &memSeries{labels: lbls}
+		s.series[key] = series
+	}
+
+	n := len(series.samples)
+	if n == 0 || series.samples[n-1].t < t {
+		series.samples = append(series.samples, sample{t: t, v: v})
+		return
+	}
+
+	i := sort.Search(n, func(i int) bool { return series.samples[i].t >= t })
+	if series.samples[i].t == t {
+		return
+	}
+	series.samples = append(series.samples, sample{})
+	copy(series.samples[i+1:], series.samples[i:])
+	series.samples[i] = sample{t: t, v: v}
+}
+
+// Querier implements storage.Queryable
+func (s *Storage) Querier(_ context.Context, mint, maxt int64) (storage.Querier, error) {
+	return &querier{storage: s, mint: mint, maxt: maxt}, nil
+}
+
+type querier struct {
+	storage    *Storage
+	mint, maxt int64
+}
+
+func (q *querier) Select(_ bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	mint, maxt := q.mint, q.maxt
+	if hints != nil {
+		mint, maxt = hints.Start, hints.End
+	}
+
+	var selected []storage.Series
+	for _, series := range q.storage.series {
+		if !matches(series.labels, matchers) {
+			continue
+		}
+
+		from := sort.Search(len(series.samples), func(i int) bool { return series.samples[i].t >= mint })
+		to := sort.Search(len(series.samples), func(i int) bool { return series.samples[i].t > maxt })
+		if from == to {
+			continue
+		}
+		selected = append(selected, &rangeSeries{labels: series.labels, samples: series.samples[from:to]})
+	}
+
+	// series are always sorted, merging them with other queriers needs it
+	sort.Slice(selected, func(i, j int) bool {
+		return labels.Compare(selected[i].Labels(), selected[j].Labels()) < 0
+	})
+
+	return &seriesSet{series: selected, i: -1}
+}
+
+func (q *querier) LabelValues(string, ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, nil
+}
+
+func (q *querier) LabelNames(...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, nil
+}
+
+func (q *querier) Close() error {
+	return nil
+}
+
+func matches(lbls labels.Labels, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(lbls.Get(m.Name)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type seriesSet struct {
+	series []storage.Series
+	i      int
+}
+
+func (s *seriesSet) Next() bool {
+	s.i++
+	return s.i < len(s.series)
+}
+
+func (s *seriesSet) At() storage.Series         { return s.series[s.i] }
+func (s *seriesSet) Err() error                 { return nil }
+func (s *seriesSet) Warnings() storage.Warnings { return nil }
+
+// rangeSeries are the samples of a series in the range queried
+type rangeSeries struct {
+	labels  labels.Labels
+	samples samples
+}
+
+func (s *rangeSeries) Labels() labels.Labels { return s.labels }
+
+func (s *rangeSeries) Iterator() chunkenc.Iterator {
+	return storage.NewListSeriesIterator(s.samples)
+}
+
+type sample struct {
+	t int64
+	v float64
+}
+
+func (s *sample) T() int64   { return s.t }
+func (s *sample) V() float64 { return s.v }
+
+// samples are given by pointer, so iterating them does not allocate
+type samples []sample
+
+func (s samples) Get(i int) tsdbutil.Sample { return &s[i] }
+func (s samples) Len() int                  { return len(s) }
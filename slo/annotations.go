@@ -0,0 +1,24 @@
+package slo
+
+// AlertAnnotations enables annotations generated for alerts: a summary, a description with the
+// objective, the burn rates and the time left until the budget is exhausted, and links to the
+// runbook and the dashboard of the SLO. Links are templates, like the annotations of the SLO,
+// which take precedence over the generated ones
+type AlertAnnotations struct {
+	RunbookURL   string `yaml:"runbookURL"`
+	DashboardURL string `yaml:"dashboardURL"`
+}
+
+// links returns the annotations linking the runbook and the dashboard of the SLO
+func (a *AlertAnnotations) links() map[string]string {
+	links := map[string]string{}
+
+	if a.RunbookURL != "" {
+		links["runbook_url"] = a.RunbookURL
+	}
+	if a.DashboardURL != "" {
+		links["dashboard_url"] = a.DashboardURL
+	}
+
+	return links
+}
@@ -0,0 +1,85 @@
+package slo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSLOGenerateAlertRulesWithAnnotations(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+alertAnnotations:
+  runbookURL: https://wiki.ops/runbooks/{{ .SLO.Name }}
+  dashboardURL: https://grafana.ops/d/slo?var-service={{ .SLO.Name }}
+slos:
+  - name: my-service
+    objectives:
+      availability: 99.9
+      latency:
+        - le: 0.1
+          target: 95
+        - le: 0.5
+          target: 99
+    errorRateRecord:
+      alertMethod: multi-window
+      expr: sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))
+    latencyRecord:
+      alertMethod: simple
+      alertWindow: 1h
+      burnRate: 2
+      expr: sum(rate(http_bucket{le="$le"}[$window]))/sum(rate(http_total[$window]))
+    annotations:
+      summary: my-service is failing
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	slo := spec.SLOS[0]
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, alertRules, 3)
+
+	assert.Equal(t, map[string]string{
+		"summary": "my-service is failing",
+		"description": "{{ $value | humanizePercentage }} of requests of my-service are failing, the objective is 99.9% of successful requests over 30d. " +
+			"The alert fires burning 14.4x the budget over 1h and 5m, or 6x over 6h and 30m, " +
+			"at the current rate the error budget is exhausted in {{ with printf \"2592 / %g\" $value | query }}{{ . | first | value | humanizeDuration }}{{ end }}.",
+		"runbook_url":   "https://wiki.ops/runbooks/my-service",
+		"dashboard_url": "https://grafana.ops/d/slo?var-service=my-service",
+	}, alertRules[0].Annotations)
+
+	assert.Equal(t, "{{ $value | humanizePercentage }} of requests of my-service are faster than {{ $labels.le }}, "+
+		"the objectives are 95% faster than 0.1 and 99% faster than 0.5 over 30d. The alert fires burning 2x the budget over 1h.",
+		alertRules[2].Annotations["description"])
+
+	perTarget := true
+	slo.LatencyRecord.PerTarget = &perTarget
+	alertRules, err = slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, alertRules, 4)
+	assert.Equal(t, "{{ $value | humanizePercentage }} of requests of my-service are faster than 0.5, the objective is 99% over 30d. "+
+		"The alert fires burning 2x the budget over 1h, "+
+		"at the current rate the latency budget is exhausted in {{ with printf \"25920 / (1 - %g)\" $value | query }}{{ . | first | value | humanizeDuration }}{{ end }}.",
+		alertRules[3].Annotations["description"])
+
+	for _, rule := range alertRules {
+		assert.Empty(t, rule.Validate())
+	}
+}
+
+func TestSLOGenerateAlertRulesWithoutAnnotations(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Availability: 99.9,
+		},
+		ErrorRateRecord: ExprBlock{
+			AlertMethod: "simple",
+			AlertWindow: "1h",
+		},
+	}
+
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, alertRules, 1)
+	assert.Empty(t, alertRules[0].Annotations)
+}
@@ -0,0 +1,252 @@
+package slo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globocom/slo-generator/methods"
+	"github.com/globocom/slo-generator/samples"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+)
+
+// DefaultBudgetWindow is the window of the error budget when objectives do not define one
+var DefaultBudgetWindow = model.Duration(30 * 24 * time.Hour)
+
+// GenerateBudgetRules returns the rules recording the SLIs over the whole SLO window, the objectives
+// of the SLO as metrics, the burn rate of every recorded window and the error budget remaining
+func (slo *SLO) GenerateBudgetRules(sloClass *Class, disableTicket bool) ([]rulefmt.RuleNode, error) {
+	group, err := slo.GenerateBudgetGroup(sloClass, disableTicket)
+	if err != nil {
+		return nil, err
+	}
+
+	return group.Rules, nil
+}
+
+// GenerateBudgetGroup returns the group slo:<name>:budget of the budget rules. The SLIs over the
+// whole SLO window read weeks of samples, so the group is evaluated at the interval of the
+// recording they are chained from instead of at every global evaluation
+func (slo *SLO) GenerateBudgetGroup(sloClass *Class, disableTicket bool) (rulefmt.RuleGroup, error) {
+	slo, err := slo.renderLabels(sloClass)
+	if err != nil {
+		return rulefmt.RuleGroup{}, err
+	}
+	disableTicket = sloClass.ticketsDisabled(disableTicket)
+	objectives := slo.objectives(sloClass)
+	budgetWindow := objectives.budgetWindow()
+
+	groups, err := slo.sampleGroups(sloClass, disableTicket)
+	if err != nil {
+		return rulefmt.RuleGroup{}, err
+	}
+	var windows []string
+	for _, group := range groups {
+		windows = append(windows, group.Buckets...)
+	}
+	if err := sortBuckets(windows); err != nil {
+		return rulefmt.RuleGroup{}, slo.generateError("", "windows", err)
+	}
+	source, interval, err := budgetSource(groups)
+	if err != nil {
+		return rulefmt.RuleGroup{}, slo.generateError("", "samples", err)
+	}
+
+	var rules []rulefmt.RuleNode
+
+	if slo.ErrorRateRecord.HasSLI() && len(windows) > 0 {
+		rules = append(rules, slo.windowRules("error", "slo:service_errors_total", source, budgetWindow.String())...)
+	}
+
+	if objectives.Availability > 0 {
+		rules = append(rules, slo.recordRule("slo:objective:availability", "vector("+formatRatio(objectives.Availability)+")", nil))
+
+		if slo.ErrorRateRecord.HasSLI() && len(windows) > 0 {
+			for _, window := range windows {
+				expr := fmt.Sprintf("slo:service_errors_total:ratio_rate_%s%s / %s (1 - slo:objective:availability%s)",
+					window, slo.selector(), slo.matching(), slo.selector())
+				rules = append(rules, slo.recordRule("slo:error_budget:burn_rate_"+window, expr, nil))
+			}
+
+			expr := fmt.Sprintf("1 - slo:sli_error:ratio_rate_%s%s / %s (1 - slo:objective:availability%s)",
+				budgetWindow, slo.selector(), slo.matching(), slo.selector())
+			rules = append(rules, slo.recordRule("slo:error_budget:remaining", expr, nil))
+		}
+	}
+
+	if slo.hasLatencySLI() && len(windows) > 0 {
+		rules = append(rules, slo.windowRules("latency", "slo:service_latency", source, budgetWindow.String())...)
+	}
+
+	if len(objectives.Latency) > 0 {
+		for _, target := range objectives.Latency {
+			rules = append(rules, slo.recordRule("slo:objective:latency", "vector("+formatRatio(target.Target)+")", map[string]string{"le": target.LE}))
+		}
+
+		if slo.hasLatencySLI() && len(windows) > 0 {
+			for _, window := range windows {
+				expr := fmt.Sprintf("(1 - slo:service_latency:ratio_rate_%s%s) / %s (1 - slo:objective:latency%s)",
+					window, slo.selector(), slo.matching("le"), slo.selector())
+				rules = append(rules, slo.recordRule("slo:latency_budget:burn_rate_"+window, expr, nil))
+			}
+
+			expr := fmt.Sprintf("1 - (1 - slo:sli_latency:ratio_rate_%s%s) / %s (1 - slo:objective:latency%s)",
+				budgetWindow, slo.selector(), slo.matching("le"), slo.selector())
+			rules = append(rules, slo.recordRule("slo:latency_budget:remaining", expr, nil))
+		}
+	}
+
+	for _, rule := range rules {
+		if err := checkExpr(rule.Expr.Value, "", "record "+rule.Record.Value); err != nil {
+			return rulefmt.RuleGroup{}, slo.generateError("", "objectives", err)
+		}
+	}
+
+	return rulefmt.RuleGroup{
+		Name:     "slo:" + slo.Name + ":budget",
+		Interval: interval,
+		Rules:    rules,
+	}, nil
+}
+
+// maxBudgetSource is the longest window the SLIs over the whole SLO window are aggregated from,
+// each sample of it spans the window, smoothing the SLI over one more window at its start
+const maxBudgetSource = model.Duration(24 * time.Hour)
+
+// budgetSource returns the recorded window the SLIs over the whole SLO window are aggregated from
+// and the interval of the budget group. They read every sample of that window, so it is the
+// shortest window of the group evaluated the least often, like the 1d of the daily group, and the
+// budget group is evaluated at the interval of that group, at least every derivedInterval
+func budgetSource(groups []samples.Sample) (string, model.Duration, error) {
+	var (
+		source         string
+		sourceDuration model.Duration
+		sourceInterval model.Duration
+	)
+
+	for _, group := range groups {
+		interval, err := model.ParseDuration(group.Interval)
+		if err != nil {
+			return "", 0, fmt.Errorf("interval of sample %s: %w", group.Name, err)
+		}
+		for _, bucket := range group.Buckets {
+			duration, err := model.ParseDuration(bucket)
+			if err != nil {
+				return "", 0, err
+			}
+
+			better := interval > sourceInterval || (interval == sourceInterval && duration < sourceDuration)
+			if (duration <= maxBudgetSource) != (sourceDuration <= maxBudgetSource) {
+				better = duration < sourceDuration
+			}
+			if source == "" || better {
+				source, sourceDuration, sourceInterval = bucket, duration, interval
+			}
+		}
+	}
+
+	if sourceInterval < derivedInterval {
+		return source, derivedInterval, nil
+	}
+
+	return source, sourceInterval, nil
+}
+
+// hasBudgetRules reports whether budget rules are generated for the SLO
+func (slo *SLO) hasBudgetRules(objectives *Objectives) bool {
+	return slo.ErrorRateRecord.HasSLI() || slo.hasLatencySLI() || objectives.Availability > 0 || len(objectives.Latency) > 0
+}
+
+// ErrorBudget returns the ratio of errors allowed by the availability objective and the window
+// of the budget, taking the objectives not set by the SLO from its class
+func (slo *SLO) ErrorBudget(sloClass *Class) (float64, time.Duration) {
+	objectives := slo.objectives(sloClass)
+	return (100 - float64(objectives.Availability)) / 100, time.Duration(objectives.budgetWindow())
+}
+
+// LatencyBudget returns the latency objectives and the window of the budget, taking the
+// objectives not set by the SLO from its class
+func (slo *SLO) LatencyBudget(sloClass *Class) ([]methods.LatencyTarget, time.Duration) {
+	objectives := slo.objectives(sloClass)
+	return objectives.Latency, time.Duration(objectives.budgetWindow())
+}
+
+// budgetWindow returns the window of the error budget, DefaultBudgetWindow when not defined
+func (o *Objectives) budgetWindow() model.Duration {
+	if o.Window == 0 {
+		return DefaultBudgetWindow
+	}
+
+	return o.Window
+}
+
+// windowRules returns the rules recording a SLI over the whole SLO window, recording rate(...[30d])
+// directly is too expensive, so it is aggregated from the source window recorded. When the traffic
+// is recorded, each sample is weighted by its traffic: the ratio of the sums of weighted samples and
+// traffic, written with averages since both series may be evaluated at different intervals.
+// Otherwise samples are simply averaged
+func (slo *SLO) windowRules(sli, metric, source, window string) []rulefmt.RuleNode {
+	ratio := fmt.Sprintf("%s:ratio_rate_%s%s", metric, source, slo.selector())
+
+	if sli == "error" && slo.ErrorRateRecord.IsEventBased() {
+		// rates of errors and requests are recorded in the same group, so their samples match
+		return []rulefmt.RuleNode{
+			slo.recordRule(fmt.Sprintf("slo:sli_%s:ratio_rate_%s", sli, window), fmt.Sprintf("sum_over_time(slo:service_errors:rate_%s%s[%s]) / sum_over_time(slo:service_requests:rate_%s%s[%s])",
+				source, slo.selector(), window, source, slo.selector(), window), nil),
+		}
+	}
+
+	if slo.TrafficRateRecord.Expr == "" {
+		return []rulefmt.RuleNode{
+			slo.recordRule(fmt.Sprintf("slo:sli_%s:ratio_rate_%s", sli, window), fmt.Sprintf("avg_over_time(%s[%s])", ratio, window), nil),
+		}
+	}
+
+	traffic := fmt.Sprintf("slo:service_traffic:ratio_rate_%s%s", source, slo.selector())
+	weighted := fmt.Sprintf("slo:sli_%s:weighted_rate_%s", sli, source)
+
+	return []rulefmt.RuleNode{
+		slo.recordRule(weighted, fmt.Sprintf("%s * %s %s", ratio, slo.matching(), traffic), nil),
+		slo.recordRule(fmt.Sprintf("slo:sli_%s:ratio_rate_%s", sli, window), fmt.Sprintf("avg_over_time(%s%s[%s]) / %s avg_over_time(%s[%s])",
+			weighted, slo.selector(), window, slo.matching(), traffic, window), nil),
+	}
+}
+
+func (slo *SLO) recordRule(record, expr string, extraLabels map[string]string) rulefmt.RuleNode {
+	rule := rulefmt.RuleNode{
+		Labels: slo.labels(),
+	}
+	for key, value := range extraLabels {
+		rule.Labels[key] = value
+	}
+	rule.Record.SetString(record)
+	rule.Expr.SetString(expr)
+
+	return rule
+}
+
+// selector returns the label matchers of the series recorded for the SLO
+func (slo *SLO) selector() string {
+	return labels.FromMap(slo.labels()).String()
+}
+
+// matching returns the vector matching used to divide series of the SLO by its objectives
+func (slo *SLO) matching(extraLabels ...string) string {
+	names := extraLabels
+	for name := range slo.labels() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return fmt.Sprintf("on(%s) group_left()", strings.Join(names, ", "))
+}
+
+// formatRatio writes a percentage as a ratio, without float rounding noise
+func formatRatio(p methods.Percent) string {
+	return strconv.FormatFloat(math.Round(p.Ratio()*1e9)/1e9, 'f', -1, 64)
+}
@@ -0,0 +1,210 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/globocom/slo-generator/methods"
+	"github.com/globocom/slo-generator/samples"
+)
+
+func TestSLOGenerateBudgetRules(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Availability: 99.9,
+			Window:       model.Duration(28 * 24 * time.Hour),
+			Latency: []methods.LatencyTarget{
+				{LE: "0.1", Target: 95},
+			},
+		},
+		TrafficRateRecord: ExprBlock{
+			Expr: "sum(rate(http_total[$window]))",
+		},
+		ErrorRateRecord: ExprBlock{
+			Expr: "sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))",
+		},
+		LatencyRecord: ExprBlock{
+			Expr: "sum(rate(http_bucket{le=\"$le\"}[$window]))/sum(rate(http_total[$window]))",
+		},
+		Labels: map[string]string{
+			"team": "team-avengers",
+		},
+		Samples: &samples.Config{
+			Groups: []samples.Sample{
+				{Name: "short", Interval: "30s", Buckets: []string{"1h", "5m"}},
+			},
+		},
+	}
+
+	rules, err := slo.GenerateBudgetRules(nil, false)
+	assert.NoError(t, err)
+
+	type record struct {
+		Record string
+		Expr   string
+		Labels map[string]string
+	}
+	records := []record{}
+	for _, rule := range rules {
+		records = append(records, record{Record: rule.Record.Value, Expr: rule.Expr.Value, Labels: rule.Labels})
+	}
+
+	labels := map[string]string{"service": "my-service", "team": "team-avengers"}
+	assert.Equal(t, []record{
+		{
+			Record: "slo:sli_error:weighted_rate_5m",
+			Expr:   "slo:service_errors_total:ratio_rate_5m{service=\"my-service\", team=\"team-avengers\"} * on(service, team) group_left() slo:service_traffic:ratio_rate_5m{service=\"my-service\", team=\"team-avengers\"}",
+			Labels: labels,
+		},
+		{
+			Record: "slo:sli_error:ratio_rate_4w",
+			Expr:   "avg_over_time(slo:sli_error:weighted_rate_5m{service=\"my-service\", team=\"team-avengers\"}[4w]) / on(service, team) group_left() avg_over_time(slo:service_traffic:ratio_rate_5m{service=\"my-service\", team=\"team-avengers\"}[4w])",
+			Labels: labels,
+		},
+		{
+			Record: "slo:objective:availability",
+			Expr:   "vector(0.999)",
+			Labels: labels,
+		},
+		{
+			Record: "slo:error_budget:burn_rate_5m",
+			Expr:   "slo:service_errors_total:ratio_rate_5m{service=\"my-service\", team=\"team-avengers\"} / on(service, team) group_left() (1 - slo:objective:availability{service=\"my-service\", team=\"team-avengers\"})",
+			Labels: labels,
+		},
+		{
+			Record: "slo:error_budget:burn_rate_1h",
+			Expr:   "slo:service_errors_total:ratio_rate_1h{service=\"my-service\", team=\"team-avengers\"} / on(service, team) group_left() (1 - slo:objective:availability{service=\"my-service\", team=\"team-avengers\"})",
+			Labels: labels,
+		},
+		{
+			Record: "slo:error_budget:remaining",
+			Expr:   "1 - slo:sli_error:ratio_rate_4w{service=\"my-service\", team=\"team-avengers\"} / on(service, team) group_left() (1 - slo:objective:availability{service=\"my-service\", team=\"team-avengers\"})",
+			Labels: labels,
+		},
+		{
+			Record: "slo:sli_latency:weighted_rate_5m",
+			Expr:   "slo:service_latency:ratio_rate_5m{service=\"my-service\", team=\"team-avengers\"} * on(service, team) group_left() slo:service_traffic:ratio_rate_5m{service=\"my-service\", team=\"team-avengers\"}",
+			Labels: labels,
+		},
+		{
+			Record: "slo:sli_latency:ratio_rate_4w",
+			Expr:   "avg_over_time(slo:sli_latency:weighted_rate_5m{service=\"my-service\", team=\"team-avengers\"}[4w]) / on(service, team) group_left() avg_over_time(slo:service_traffic:ratio_rate_5m{service=\"my-service\", team=\"team-avengers\"}[4w])",
+			Labels: labels,
+		},
+		{
+			Record: "slo:objective:latency",
+			Expr:   "vector(0.95)",
+			Labels: map[string]string{"service": "my-service", "team": "team-avengers", "le": "0.1"},
+		},
+		{
+			Record: "slo:latency_budget:burn_rate_5m",
+			Expr:   "(1 - slo:service_latency:ratio_rate_5m{service=\"my-service\", team=\"team-avengers\"}) / on(le, service, team) group_left() (1 - slo:objective:latency{service=\"my-service\", team=\"team-avengers\"})",
+			Labels: labels,
+		},
+		{
+			Record: "slo:latency_budget:burn_rate_1h",
+			Expr:   "(1 - slo:service_latency:ratio_rate_1h{service=\"my-service\", team=\"team-avengers\"}) / on(le, service, team) group_left() (1 - slo:objective:latency{service=\"my-service\", team=\"team-avengers\"})",
+			Labels: labels,
+		},
+		{
+			Record: "slo:latency_budget:remaining",
+			Expr:   "1 - (1 - slo:sli_latency:ratio_rate_4w{service=\"my-service\", team=\"team-avengers\"}) / on(le, service, team) group_left() (1 - slo:objective:latency{service=\"my-service\", team=\"team-avengers\"})",
+			Labels: labels,
+		},
+	}, records)
+}
+
+func TestSLOGenerateBudgetRulesWithoutExpr(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Availability: 99.95,
+		},
+	}
+
+	rules, err := slo.GenerateBudgetRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 1)
+	assert.Equal(t, "slo:objective:availability", rules[0].Record.Value)
+	assert.Equal(t, "vector(0.9995)", rules[0].Expr.Value)
+}
+
+func TestSLOGenerateBudgetRulesWithoutTraffic(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Availability: 99,
+		},
+		ErrorRateRecord: ExprBlock{
+			Expr: "sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))",
+		},
+	}
+
+	rules, err := slo.GenerateBudgetRules(nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "slo:sli_error:ratio_rate_30d", rules[0].Record.Value)
+	assert.Equal(t, "avg_over_time(slo:service_errors_total:ratio_rate_1d{service=\"my-service\"}[30d])", rules[0].Expr.Value)
+}
+
+func TestSLOGenerateBudgetRulesWithEvents(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Availability: 99.9,
+		},
+		ErrorRateRecord: ExprBlock{
+			BadEvents:   "http_total{status=~\"5..\"}",
+			TotalEvents: "http_total",
+		},
+	}
+
+	rules, err := slo.GenerateBudgetRules(nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "slo:sli_error:ratio_rate_30d", rules[0].Record.Value)
+	assert.Equal(t, "sum_over_time(slo:service_errors:rate_1d{service=\"my-service\"}[30d]) / sum_over_time(slo:service_requests:rate_1d{service=\"my-service\"}[30d])", rules[0].Expr.Value)
+}
+
+func TestSLOGenerateBudgetGroup(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Availability: 99.9,
+		},
+		ErrorRateRecord: ExprBlock{
+			Expr: "sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))",
+		},
+	}
+
+	// the daily group is evaluated the least often, its shortest window is the source
+	group, err := slo.GenerateBudgetGroup(nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "slo:my-service:budget", group.Name)
+	assert.Equal(t, model.Duration(5*time.Minute), group.Interval)
+	assert.Equal(t, "avg_over_time(slo:service_errors_total:ratio_rate_1d{service=\"my-service\"}[30d])", group.Rules[0].Expr.Value)
+
+	// budget rules are evaluated at least every 5m, even when every window is evaluated more often
+	slo.Samples = &samples.Config{
+		Groups: []samples.Sample{
+			{Name: "short", Interval: "30s", Buckets: []string{"1h", "5m"}},
+			{Name: "medium", Interval: "1m", Buckets: []string{"6h", "2h"}},
+		},
+	}
+	group, err = slo.GenerateBudgetGroup(nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, model.Duration(5*time.Minute), group.Interval)
+	assert.Equal(t, "avg_over_time(slo:service_errors_total:ratio_rate_2h{service=\"my-service\"}[30d])", group.Rules[0].Expr.Value)
+
+	// and at the interval of the coarsest group when it is longer, windows longer than a day are not used
+	slo.Samples.Groups = append(slo.Samples.Groups,
+		samples.Sample{Name: "daily", Interval: "15m", Buckets: []string{"12h", "3d"}},
+		samples.Sample{Name: "weekly", Interval: "1h", Buckets: []string{"7d"}},
+	)
+	group, err = slo.GenerateBudgetGroup(nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, model.Duration(15*time.Minute), group.Interval)
+	assert.Equal(t, "avg_over_time(slo:service_errors_total:ratio_rate_12h{service=\"my-service\"}[30d])", group.Rules[0].Expr.Value)
+}
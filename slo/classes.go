@@ -1,13 +1,36 @@
 package slo
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/globocom/slo-generator/samples"
+)
 
 // Class represents a template of objectives
 // this is important to achieve scalable SLO policies
 // read more at: https://landing.google.com/sre/workbook/chapters/alerting-on-slos/#alerting_at_scale
 type Class struct {
-	Name       string     `yaml:"name"`
-	Objectives Objectives `yaml:"objectives"`
+	Name                  string               `yaml:"name"`
+	Extends               string               `yaml:"extends"` // fields not set are inherited from this class
+	Objectives            Objectives           `yaml:"objectives"`
+	Samples               *samples.Config      `yaml:"samples"`
+	LatencyQuantileRecord *ClassQuantileRecord `yaml:"latencyQuantileRecord"`
+
+	// alerting policy of SLOs of the class, only alerting options are used,
+	// options set by the SLOs take precedence
+	ErrorRateRecord *ExprBlock        `yaml:"errorRateRecord"`
+	LatencyRecord   *ExprBlock        `yaml:"latencyRecord"`
+	DisableTicket   *bool             `yaml:"disableTicket"` // disables alerts of kind ticket of SLOs of the class
+	Labels          map[string]string `yaml:"labels"`        // default labels of alerts, labels of SLOs take precedence
+	Annotations     map[string]string `yaml:"annotations"`   // default annotations of alerts, annotations of SLOs take precedence
+
+	source source
+}
+
+// ClassQuantileRecord overrides the quantiles recorded by latencyQuantileRecord of SLOs of the class
+type ClassQuantileRecord struct {
+	Quantiles []float64 `yaml:"quantiles"`
 }
 
 type ClassesDefinition struct {
@@ -16,16 +39,192 @@ type ClassesDefinition struct {
 
 type Classes []Class
 
-// FindClass finds for a given name, if not found return an error
+// FindClass finds for a given name, if not found return an error.
+// The class returned has the fields inherited from the classes it extends
 func (c Classes) FindClass(name string) (*Class, error) {
 	if name == "" {
 		return nil, nil
 	}
-	for _, class := range c {
-		if class.Name == name {
-			return &class, nil
+
+	chain, err := c.classChain(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// from the most generic class to the one requested
+	class := *chain[len(chain)-1]
+	for i := len(chain) - 2; i >= 0; i-- {
+		class = chain[i].inherit(class)
+	}
+
+	return &class, nil
+}
+
+// classChain returns the class and the classes it extends, from the class to the most generic one
+func (c Classes) classChain(name string) ([]*Class, error) {
+	var (
+		chain []*Class
+		names []string
+	)
+
+	for name != "" {
+		class := c.find(name)
+		if class == nil {
+			if len(chain) == 0 {
+				return nil, fmt.Errorf("SLO class %q is not found", name)
+			}
+			return nil, fmt.Errorf("SLO class %q extends %q, which is not found", chain[len(chain)-1].Name, name)
 		}
+
+		for _, previous := range names {
+			if previous == name {
+				return nil, fmt.Errorf("SLO class %q extends itself: %s", names[0], strings.Join(append(names, name), " -> "))
+			}
+		}
+
+		chain = append(chain, class)
+		names = append(names, name)
+		name = class.Extends
+	}
+
+	return chain, nil
+}
+
+func (c Classes) find(name string) *Class {
+	for i := range c {
+		if c[i].Name == name {
+			return &c[i]
+		}
+	}
+
+	return nil
+}
+
+// inherit returns the class with the fields it does not set taken from its parent
+func (class Class) inherit(parent Class) Class {
+	class.Objectives = class.Objectives.override(parent.Objectives)
+	if class.Samples == nil {
+		class.Samples = parent.Samples
+	}
+	if class.LatencyQuantileRecord == nil {
+		class.LatencyQuantileRecord = parent.LatencyQuantileRecord
+	}
+	class.ErrorRateRecord = inheritPolicy(class.ErrorRateRecord, parent.ErrorRateRecord)
+	class.LatencyRecord = inheritPolicy(class.LatencyRecord, parent.LatencyRecord)
+	if class.DisableTicket == nil {
+		class.DisableTicket = parent.DisableTicket
+	}
+	class.Labels = mergeMetadata(parent.Labels, class.Labels)
+	class.Annotations = mergeMetadata(parent.Annotations, class.Annotations)
+
+	return class
+}
+
+func inheritPolicy(policy, parent *ExprBlock) *ExprBlock {
+	if policy == nil {
+		return parent
+	}
+	if parent == nil {
+		return policy
+	}
+
+	merged := policy.withPolicy(parent)
+	return &merged
+}
+
+// mergeMetadata returns labels or annotations of base overridden by the ones of values
+func mergeMetadata(base, values map[string]string) map[string]string {
+	if len(base) == 0 {
+		return values
+	}
+	if len(values) == 0 {
+		return base
+	}
+
+	merged := make(map[string]string, len(base)+len(values))
+	for name, value := range base {
+		merged[name] = value
+	}
+	for name, value := range values {
+		merged[name] = value
+	}
+
+	return merged
+}
+
+// withPolicy returns the block with the alerting options it does not set taken from policy
+func (block ExprBlock) withPolicy(policy *ExprBlock) ExprBlock {
+	if block.AlertMethod == "" {
+		block.AlertMethod = policy.AlertMethod
+	}
+	if block.AlertWindow == "" {
+		block.AlertWindow = policy.AlertWindow
+	}
+	if block.BurnRate == 0 {
+		block.BurnRate = policy.BurnRate
+	}
+	if block.AlertWait == "" {
+		block.AlertWait = policy.AlertWait
+	}
+	if block.Severity == "" {
+		block.Severity = policy.Severity
+	}
+	if block.Windows == nil {
+		block.Windows = policy.Windows
+	}
+	if block.ShortWindow == nil {
+		block.ShortWindow = policy.ShortWindow
+	}
+	if block.Targets == nil {
+		block.Targets = policy.Targets
+	}
+	if block.PerTarget == nil {
+		block.PerTarget = policy.PerTarget
+	}
+
+	return block
+}
+
+// withClass returns the SLO with the alerting policy, labels and annotations of its class
+func (slo *SLO) withClass(sloClass *Class) *SLO {
+	if sloClass == nil {
+		return slo
+	}
+
+	result := *slo
+	if sloClass.ErrorRateRecord != nil {
+		result.ErrorRateRecord = slo.ErrorRateRecord.withPolicy(sloClass.ErrorRateRecord)
+	}
+	if sloClass.LatencyRecord != nil {
+		result.LatencyRecord = slo.LatencyRecord.withPolicy(sloClass.LatencyRecord)
+	}
+	result.Labels = mergeMetadata(sloClass.Labels, slo.Labels)
+	result.Annotations = mergeMetadata(sloClass.Annotations, slo.Annotations)
+
+	return &result
+}
+
+// ticketsDisabled returns whether alerts of kind ticket are disabled for SLOs of the class,
+// they are always disabled when disableTicket is given
+func (c *Class) ticketsDisabled(disableTicket bool) bool {
+	if disableTicket || c == nil || c.DisableTicket == nil {
+		return disableTicket
+	}
+
+	return *c.DisableTicket
+}
+
+// override returns the objectives with the fields they do not set taken from base
+func (o Objectives) override(base Objectives) Objectives {
+	if o.Availability == 0 {
+		o.Availability = base.Availability
+	}
+	if o.Latency == nil {
+		o.Latency = base.Latency
+	}
+	if o.Window == 0 {
+		o.Window = base.Window
 	}
 
-	return nil, fmt.Errorf("SLO class %q is not found", name)
+	return o
 }
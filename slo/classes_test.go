@@ -2,8 +2,13 @@ package slo
 
 import (
 	"testing"
+	"time"
 
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/globocom/slo-generator/methods"
 )
 
 func TestFindClass(t *testing.T) {
@@ -26,3 +31,221 @@ func TestFindClass(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Nil(t, class)
 }
+
+func TestFindClassExtends(t *testing.T) {
+	classes := Classes{
+		{
+			Name: "BASE",
+			Objectives: Objectives{
+				Availability: 99,
+				Window:       model.Duration(28 * 24 * time.Hour),
+				Latency:      []methods.LatencyTarget{{LE: "0.5", Target: 99}},
+			},
+			LatencyQuantileRecord: &ClassQuantileRecord{Quantiles: []float64{0.9}},
+		},
+		{
+			Name:    "HIGH",
+			Extends: "BASE",
+			Objectives: Objectives{
+				Availability: 99.9,
+			},
+		},
+		{
+			Name:    "CRITICAL",
+			Extends: "HIGH",
+			Objectives: Objectives{
+				Latency: []methods.LatencyTarget{},
+			},
+		},
+	}
+
+	class, err := classes.FindClass("CRITICAL")
+	assert.NoError(t, err)
+	assert.Equal(t, &Class{
+		Name:    "CRITICAL",
+		Extends: "HIGH",
+		Objectives: Objectives{
+			Availability: 99.9,
+			Window:       model.Duration(28 * 24 * time.Hour),
+			Latency:      []methods.LatencyTarget{},
+		},
+		LatencyQuantileRecord: &ClassQuantileRecord{Quantiles: []float64{0.9}},
+	}, class)
+	// classes extended are not modified
+	assert.Equal(t, methods.Percent(99), classes[0].Objectives.Availability)
+
+	classes = append(classes, Class{Name: "A", Extends: "B"}, Class{Name: "B", Extends: "A"}, Class{Name: "C", Extends: "D"})
+
+	_, err = classes.FindClass("A")
+	assert.EqualError(t, err, "SLO class \"A\" extends itself: A -> B -> A")
+
+	_, err = classes.FindClass("C")
+	assert.EqualError(t, err, "SLO class \"C\" extends \"D\", which is not found")
+}
+
+func TestSLOObjectivesOverrideClass(t *testing.T) {
+	class := &Class{
+		Name: "HIGH",
+		Objectives: Objectives{
+			Availability: 99.9,
+			Latency:      []methods.LatencyTarget{{LE: "0.5", Target: 99}},
+		},
+	}
+	slo := &SLO{
+		Name:  "my-service",
+		Class: "HIGH",
+		Objectives: Objectives{
+			Latency: []methods.LatencyTarget{{LE: "0.1", Target: 95}},
+		},
+		ErrorRateRecord: ExprBlock{
+			AlertMethod: "simple",
+			AlertWindow: "1h",
+		},
+		LatencyRecord: ExprBlock{
+			AlertMethod: "simple",
+			AlertWindow: "1h",
+		},
+	}
+
+	assert.Equal(t, Objectives{
+		Availability: 99.9,
+		Latency:      []methods.LatencyTarget{{LE: "0.1", Target: 95}},
+	}, slo.objectives(class))
+
+	alertRules, err := slo.GenerateAlertRules(class, false)
+	assert.NoError(t, err)
+	assert.Len(t, alertRules, 2)
+	assert.Equal(t, "slo:service_errors_total:ratio_rate_1h{service=\"my-service\"} > 1 * 0.001", alertRules[0].Expr.Value)
+	assert.Equal(t, "slo:service_latency:ratio_rate_1h{le=\"0.1\", service=\"my-service\"} < 1 - 1 * 0.05", alertRules[1].Expr.Value)
+}
+
+func TestSLOGenerateAlertRulesWithClassPolicy(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+classes:
+  - name: BASE
+    objectives:
+      availability: 99.9
+      window: 30d
+      latency:
+        - le: 0.5
+          target: 99
+    errorRateRecord:
+      alertMethod: multi-window
+      shortWindow: false
+      windows:
+        - duration: 1h
+          consumption: 2
+          notification: page
+        - duration: 1d
+          consumption: 10
+          notification: ticket
+    labels:
+      team: sre
+      channel: sre-alerts
+  - name: HIGH
+    extends: BASE
+    disableTicket: true
+    latencyRecord:
+      alertMethod: simple
+      alertWindow: 1h
+      burnRate: 2
+    annotations:
+      runbook: https://wiki.ops/{{ .SLO.Name }}
+slos:
+  - name: my-service
+    class: HIGH
+    errorRateRecord:
+      expr: sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))
+    latencyRecord:
+      expr: sum(rate(http_bucket{le="$le"}[$window]))/sum(rate(http_total[$window]))
+      alertWait: 5m
+    labels:
+      channel: my-channel
+`), "slo.yml", true)
+	assert.NoError(t, err)
+	assert.NoError(t, spec.Validate())
+
+	slo := spec.SLOS[0]
+	class, err := spec.Classes.FindClass(slo.Class)
+	assert.NoError(t, err)
+
+	alertRules, err := slo.GenerateAlertRules(class, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []rulefmt.RuleNode{
+		ruleNode(rulefmt.Rule{
+			Alert:       "slo:my-service.errors.page",
+			Expr:        "slo:service_errors_total:ratio_rate_1h{service=\"my-service\"} > (14.4 * 0.001)",
+			Labels:      map[string]string{"severity": "page", "signal": "error", "team": "sre", "channel": "my-channel"},
+			Annotations: map[string]string{"runbook": "https://wiki.ops/my-service"},
+		}),
+		ruleNode(rulefmt.Rule{
+			Alert:       "slo:my-service.latency.page",
+			Expr:        "slo:service_latency:ratio_rate_1h{le=\"0.5\", service=\"my-service\"} < 1 - 2 * 0.01",
+			For:         model.Duration(5 * time.Minute),
+			Labels:      map[string]string{"severity": "page", "signal": "latency", "team": "sre", "channel": "my-channel"},
+			Annotations: map[string]string{"runbook": "https://wiki.ops/my-service"},
+		}),
+	}, alertRules)
+
+	// the 1d window of tickets is not recorded, tickets are disabled by the class
+	groups, err := slo.GenerateGroupRules(class, false)
+	assert.NoError(t, err)
+	for _, group := range groups {
+		for _, rule := range group.Rules {
+			assert.NotContains(t, rule.Record.Value, "_1d")
+		}
+	}
+
+	// the SLO and the class extended are not modified
+	assert.Equal(t, "", slo.ErrorRateRecord.AlertMethod)
+	assert.Equal(t, map[string]string{"channel": "my-channel"}, slo.Labels)
+	assert.Nil(t, spec.Classes[0].LatencyRecord)
+}
+
+func TestSLOAlertPerTargetOverridesClass(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+classes:
+  - name: BASE
+    objectives:
+      availability: 99.9
+      latency:
+        - le: 0.1
+          target: 95
+        - le: 0.5
+          target: 99
+    latencyRecord:
+      alertMethod: simple
+      alertWindow: 1h
+      alertPerTarget: true
+  - name: HIGH
+    extends: BASE
+slos:
+  - name: per-target
+    class: HIGH
+    latencyRecord:
+      expr: sum(rate(http_bucket{le="$le"}[$window]))/sum(rate(http_total[$window]))
+  - name: together
+    class: HIGH
+    latencyRecord:
+      expr: sum(rate(http_bucket{le="$le"}[$window]))/sum(rate(http_total[$window]))
+      alertPerTarget: false
+`), "slo.yml", true)
+	assert.NoError(t, err)
+	assert.NoError(t, spec.Validate())
+
+	class, err := spec.Classes.FindClass("HIGH")
+	assert.NoError(t, err)
+
+	// alertPerTarget of the class is inherited through extends
+	alertRules, err := spec.SLOS[0].GenerateAlertRules(class, false)
+	assert.NoError(t, err)
+	assert.Len(t, alertRules, 2)
+
+	// and false in the SLO takes precedence over true in the class
+	alertRules, err = spec.SLOS[1].GenerateAlertRules(class, false)
+	assert.NoError(t, err)
+	if assert.Len(t, alertRules, 1) {
+		assert.Equal(t, "slo:service_latency:ratio_rate_1h{le=\"0.1\", service=\"together\"} < 1 - 1 * 0.05 or "+
+			"slo:service_latency:ratio_rate_1h{le=\"0.5\", service=\"together\"} < 1 - 1 * 0.01", alertRules[0].Expr.Value)
+	}
+}
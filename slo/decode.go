@@ -0,0 +1,208 @@
+package slo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// source keeps where an item was declared, used to point validation errors to the YAML
+type source struct {
+	file string
+	node *yaml.Node
+}
+
+// ReadSLOSpec reads a SLO specification from a YAML file,
+// when strict is enabled unknown fields are rejected
+func ReadSLOSpec(path string, strict bool) (*SLOSpec, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSLOSpec(content, path, strict)
+}
+
+// ParseSLOSpec decodes a SLO specification keeping the position of every SLO and class
+func ParseSLOSpec(content []byte, file string, strict bool) (*SLOSpec, error) {
+	spec := &SLOSpec{}
+	root, err := decode(content, file, spec, strict)
+	if err != nil {
+		return nil, err
+	}
+
+	spec.setSources(root, file)
+
+	return spec, nil
+}
+
+// ParseSLOSpecs decodes every document of a YAML stream as a SLO specification,
+// documents are separated by ---
+func ParseSLOSpecs(content []byte, file string, strict bool) ([]*SLOSpec, error) {
+	var specs []*SLOSpec
+	roots, err := decodeDocuments(content, file, func() interface{} {
+		specs = append(specs, &SLOSpec{})
+		return specs[len(specs)-1]
+	}, strict)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, root := range roots {
+		specs[i].setSources(root, file)
+	}
+
+	return specs, nil
+}
+
+// setSources keeps where the specification, its SLOs and classes were declared,
+// SLOs are filled with the settings of the specification
+func (spec *SLOSpec) setSources(root *yaml.Node, file string) {
+	spec.source = source{file: file, node: root}
+	for i := range spec.SLOS {
+		spec.SLOS[i].Samples = spec.Samples
+		spec.SLOS[i].AlertAnnotations = spec.AlertAnnotations
+	}
+	for i, node := range sequenceItems(root, "slos") {
+		if i < len(spec.SLOS) {
+			spec.SLOS[i].source = source{file: file, node: node}
+		}
+	}
+	spec.Classes.setSources(root, file)
+}
+
+// ReadClassesDefinition reads SLO classes from a YAML file,
+// when strict is enabled unknown fields are rejected
+func ReadClassesDefinition(path string, strict bool) (*ClassesDefinition, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseClassesDefinition(content, path, strict)
+}
+
+// ParseClassesDefinition decodes SLO classes keeping the position of every class
+func ParseClassesDefinition(content []byte, file string, strict bool) (*ClassesDefinition, error) {
+	definition := &ClassesDefinition{
+		Classes: []Class{},
+	}
+	root, err := decode(content, file, definition, strict)
+	if err != nil {
+		return nil, err
+	}
+	definition.Classes.setSources(root, file)
+
+	return definition, nil
+}
+
+func (c Classes) setSources(root *yaml.Node, file string) {
+	for i, node := range sequenceItems(root, "classes") {
+		if i < len(c) {
+			c[i].source = source{file: file, node: node}
+		}
+	}
+}
+
+func decode(content []byte, file string, target interface{}, strict bool) (*yaml.Node, error) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(content, root); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(strict)
+
+	err := decoder.Decode(target)
+	if err == io.EOF {
+		return root, nil
+	}
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		suggestFields(typeErr, reflect.TypeOf(target))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return root, nil
+}
+
+// decodeDocuments decodes each document of a YAML stream into the target returned by next,
+// returning the root node of every document
+func decodeDocuments(content []byte, file string, next func() interface{}, strict bool) ([]*yaml.Node, error) {
+	nodes := yaml.NewDecoder(bytes.NewReader(content))
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(strict)
+
+	var roots []*yaml.Node
+	for {
+		root := &yaml.Node{}
+		err := nodes.Decode(root)
+		if err == io.EOF {
+			return roots, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		target := next()
+		err = decoder.Decode(target)
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			suggestFields(typeErr, reflect.TypeOf(target))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		roots = append(roots, root)
+	}
+}
+
+// sequenceItems returns the items of a sequence found by key in a mapping node
+func sequenceItems(node *yaml.Node, key string) []*yaml.Node {
+	sequence := lookupNode(node, key)
+	if sequence == nil || sequence.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	return sequence.Content
+}
+
+// lookupNode walks through the node tree following a path of mapping keys (string)
+// and sequence indexes (int), returns nil when the path is not found
+func lookupNode(node *yaml.Node, path ...interface{}) *yaml.Node {
+	for _, elem := range path {
+		if node == nil {
+			return nil
+		}
+		if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+			node = node.Content[0]
+		}
+
+		switch key := elem.(type) {
+		case string:
+			var value *yaml.Node
+			if node.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == key {
+						value = node.Content[i+1]
+						break
+					}
+				}
+			}
+			node = value
+		case int:
+			if node.Kind != yaml.SequenceNode || key >= len(node.Content) {
+				return nil
+			}
+			node = node.Content[key]
+		default:
+			return nil
+		}
+	}
+
+	return node
+}
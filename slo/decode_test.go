@@ -0,0 +1,148 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/globocom/slo-generator/methods"
+)
+
+var specWithTypo = []byte(`
+slos:
+  - name: my-service
+    objectives:
+      availability: 99.9
+    errorRateRecord:
+      alertMethod: multi-window
+      alertWindows:
+        - duration: 1h
+          consumption: 2
+          notification: page
+      exrp: sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))
+`)
+
+func TestParseSLOSpecStrict(t *testing.T) {
+	spec, err := ParseSLOSpec(specWithTypo, "slo.yml", true)
+	assert.Nil(t, spec)
+	assert.EqualError(t, err, "slo.yml: yaml: unmarshal errors:\n"+
+		"  line 8: field alertWindows not found in type slo.ExprBlock, did you mean \"alertWindow\" or \"windows\"?\n"+
+		"  line 12: field exrp not found in type slo.ExprBlock, did you mean \"expr\"?")
+}
+
+func TestParseSLOSpecNotStrict(t *testing.T) {
+	spec, err := ParseSLOSpec(specWithTypo, "slo.yml", false)
+	assert.NoError(t, err)
+	assert.Len(t, spec.SLOS, 1)
+	assert.Equal(t, "my-service", spec.SLOS[0].Name)
+	assert.Empty(t, spec.SLOS[0].ErrorRateRecord.Windows)
+}
+
+func TestParseClassesDefinitionStrict(t *testing.T) {
+	definition, err := ParseClassesDefinition([]byte(`
+classes:
+  - name: HIGH
+    objective:
+      availability: 99.9
+`), "classes.yml", true)
+	assert.Nil(t, definition)
+	assert.EqualError(t, err, "classes.yml: yaml: unmarshal errors:\n"+
+		"  line 4: field objective not found in type slo.Class, did you mean \"objectives\"?")
+
+	definition, err = ParseClassesDefinition([]byte(`
+classes:
+  - name: HIGH
+    objectives:
+      availability: 99.9
+`), "classes.yml", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "HIGH", definition.Classes[0].Name)
+	assert.Equal(t, methods.Percent(99.9), definition.Classes[0].Objectives.Availability)
+}
+
+func TestParseSLOSpecEmpty(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(""), "slo.yml", true)
+	assert.NoError(t, err)
+	assert.Empty(t, spec.SLOS)
+}
+
+func TestParseSLOSpecPercentages(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+slos:
+  - name: my-service
+    objectives:
+      availability: 99.9%
+      window: 30d
+      latency:
+        - le: 0.1
+          target: {ratio: 0.95}
+        - le: 0.5
+          target: 99
+    errorRateRecord:
+      alertMethod: multi-window
+      windows:
+        - duration: 1h
+          consumption: 2%
+          notification: page
+        - duration: 6h
+          consumption: 5
+          notification: page
+        - duration: 3d
+          consumption:
+            ratio: 0.1
+          notification: ticket
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	slo := spec.SLOS[0]
+	assert.Equal(t, methods.Percent(99.9), slo.Objectives.Availability)
+	assert.Equal(t, []methods.LatencyTarget{
+		{LE: "0.1", Target: 95},
+		{LE: "0.5", Target: 99},
+	}, slo.Objectives.Latency)
+	assert.Equal(t, []methods.Window{
+		{Duration: model.Duration(time.Hour), Consumption: 2, Notification: "page"},
+		{Duration: model.Duration(6 * time.Hour), Consumption: 5, Notification: "page"},
+		{Duration: model.Duration(72 * time.Hour), Consumption: 10, Notification: "ticket"},
+	}, slo.ErrorRateRecord.Windows)
+}
+
+func TestParseSLOSpecInvalidPercentages(t *testing.T) {
+	cases := []struct {
+		value string
+		err   string
+	}{
+		{value: "0.999", err: "slo.yml: line 5: 0.999 is ambiguous, write it as a percentage (99.9% or 0.999%) or as a ratio ({ratio: 0.999})"},
+		{value: "high", err: "slo.yml: line 5: \"high\" is not a valid percentage, use a number (2), a percentage (2%) or a ratio ({ratio: 0.02})"},
+		{value: "abc%", err: "slo.yml: line 5: \"abc%\" is not a valid percentage"},
+		{value: "{ratio: 99.9}", err: "slo.yml: line 5: ratio must be between 0 and 1, got 99.9"},
+		{value: "{percent: 99.9}", err: "slo.yml: line 5: a percentage written as mapping must only have the ratio field"},
+	}
+
+	for _, c := range cases {
+		spec, err := ParseSLOSpec([]byte(`
+slos:
+  - name: my-service
+    objectives:
+      availability: `+c.value+`
+`), "slo.yml", true)
+		assert.Nil(t, spec, c.value)
+		assert.EqualError(t, err, c.err, c.value)
+	}
+}
+
+func TestParseSLOSpecsStrict(t *testing.T) {
+	specs, err := ParseSLOSpecs([]byte(`
+slos:
+  - name: cart
+---
+slos:
+  - name: checkout
+    clas: HIGH
+`), "slo.yml", true)
+	assert.Nil(t, specs)
+	assert.EqualError(t, err, "slo.yml: yaml: unmarshal errors:\n"+
+		"  line 7: field clas not found in type slo.SLO, did you mean \"class\"?")
+}
@@ -0,0 +1,59 @@
+package slo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/globocom/slo-generator/methods"
+)
+
+// Record blocks of a SLO, used to identify where a generation error happened
+const (
+	TrafficBlock         = "traffic"
+	ErrorBlock           = "error"
+	LatencyBlock         = "latency"
+	LatencyQuantileBlock = "latencyQuantile"
+)
+
+// GenerateError is returned when rules of a SLO could not be generated
+type GenerateError struct {
+	SLO    string
+	Record string
+	Field  string
+	Err    error
+}
+
+func (e *GenerateError) Error() string {
+	msg := fmt.Sprintf("could not generate SLO %q", e.SLO)
+	if e.Record != "" {
+		msg += fmt.Sprintf(", %s record", e.Record)
+	}
+	if e.Field != "" {
+		msg += fmt.Sprintf(", field %s", e.Field)
+	}
+
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *GenerateError) Unwrap() error {
+	return e.Err
+}
+
+func (slo *SLO) generateError(record, field string, err error) error {
+	return &GenerateError{
+		SLO:    slo.Name,
+		Record: record,
+		Field:  field,
+		Err:    err,
+	}
+}
+
+// methodField returns the option that caused an alert method to fail, when known
+func methodField(err error) string {
+	var optionErr *methods.OptionError
+	if errors.As(err, &optionErr) {
+		return optionErr.Option
+	}
+
+	return ""
+}
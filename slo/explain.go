@@ -0,0 +1,225 @@
+package slo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globocom/slo-generator/methods"
+	"github.com/globocom/slo-generator/samples"
+)
+
+// Explanation is an effective value of a SLO and where it was defined
+type Explanation struct {
+	Field  string
+	Value  string
+	Origin string // SLO, class "NAME", specification or default
+}
+
+func (e Explanation) String() string {
+	return fmt.Sprintf("%s = %s (%s)", e.Field, e.Value, e.Origin)
+}
+
+// candidate is a value that may be defined in one of the places merged
+type candidate struct {
+	origin string
+	set    bool
+	value  func() string
+}
+
+type objectivesSource struct {
+	origin     string
+	objectives *Objectives
+}
+
+// Explain returns the effective values of the SLO and where each one was defined. Objectives are
+// merged from the most generic class extended to the class of the SLO, then the SLO itself.
+// Samples and quantiles of classes take precedence over the ones of the SLO
+func (slo *SLO) Explain(classes Classes) ([]Explanation, error) {
+	var chain []*Class
+	if slo.Class != "" {
+		var err error
+		chain, err = classes.classChain(slo.Class)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// objectives of the SLO override the ones of its class, which override the ones of the classes extended
+	sources := []objectivesSource{{origin: "SLO", objectives: &slo.Objectives}}
+	for _, class := range chain {
+		sources = append(sources, objectivesSource{origin: classOrigin(class), objectives: &class.Objectives})
+	}
+
+	var availability, latency, window []candidate
+	for _, source := range sources {
+		o := source.objectives
+		availability = append(availability, candidate{origin: source.origin, set: o.Availability != 0, value: func() string { return formatPercent(o.Availability) }})
+		latency = append(latency, candidate{origin: source.origin, set: o.Latency != nil, value: func() string { return formatLatency(o) }})
+		window = append(window, candidate{origin: source.origin, set: o.Window != 0, value: o.Window.String})
+	}
+	notSet := candidate{origin: "default", set: true, value: func() string { return "not set" }}
+
+	explanations := []Explanation{
+		explain("objectives.availability", append(availability, notSet)...),
+		explain("objectives.latency", append(latency, notSet)...),
+		explain("objectives.window", append(window, candidate{origin: "default", set: true, value: DefaultBudgetWindow.String})...),
+	}
+
+	var sampleCandidates []candidate
+	quantileCandidates := []candidate{
+		{origin: "SLO", set: slo.LatencyQuantileRecord.Quantiles != nil, value: func() string { return formatQuantiles(slo.LatencyQuantileRecord.Quantiles) }},
+	}
+	for _, class := range chain {
+		class := class
+		sampleCandidates = append(sampleCandidates, candidate{origin: classOrigin(class), set: class.Samples != nil, value: func() string { return formatSamples(class.Samples) }})
+		quantileCandidates = append(quantileCandidates, candidate{origin: classOrigin(class), set: class.LatencyQuantileRecord != nil && class.LatencyQuantileRecord.Quantiles != nil,
+			value: func() string { return formatQuantiles(class.LatencyQuantileRecord.Quantiles) }})
+	}
+	sampleCandidates = append(sampleCandidates,
+		candidate{origin: "specification", set: slo.Samples != nil, value: func() string { return formatSamples(slo.Samples) }},
+		candidate{origin: "default", set: true, value: func() string { return formatSamples(samples.DefaultConfig) }},
+	)
+	quantileCandidates = append(quantileCandidates, candidate{origin: "default", set: true, value: func() string { return formatQuantiles(DefaultQuantiles) }})
+
+	explanations = append(explanations,
+		explain("samples", sampleCandidates...),
+		explain("latencyQuantileRecord.quantiles", quantileCandidates...),
+	)
+
+	return explanations, nil
+}
+
+// AlertThreshold is a window alerted for a SLO, with the burn rate of the budget that fires the alert on it
+type AlertThreshold struct {
+	Signal    string // error or latency
+	LE        string // latency target with its own alerting options, empty when alerted with the others
+	Severity  methods.NotificationSeverity
+	Window    methods.Window
+	BurnRate  float64
+	Threshold string  // condition on the SLI, like "error ratio > 1.44%"
+	Value     float64 // error ratio above which the alert fires, only set for errors
+}
+
+func (t AlertThreshold) String() string {
+	signal := t.Signal
+	if t.LE != "" {
+		signal += " " + t.LE
+	}
+
+	return fmt.Sprintf("%s %s: %sx the budget over %s (%s of the budget), fires when %s",
+		signal, t.Severity, formatFloat(t.BurnRate), t.Window.Duration, formatPercent(t.Window.Consumption), t.Threshold)
+}
+
+// AlertThresholds returns the windows alerted for the SLO, with their burn rates and the
+// thresholds of the SLIs that fire the alerts, taken from the rates the alert methods build rules from
+func (slo *SLO) AlertThresholds(sloClass *Class, disableTicket bool) []AlertThreshold {
+	slo = slo.withClass(sloClass)
+	disableTicket = sloClass.ticketsDisabled(disableTicket)
+	objectives := slo.objectives(sloClass)
+	budgetWindow := time.Duration(objectives.budgetWindow())
+
+	var thresholds []AlertThreshold
+	for _, alert := range slo.alertRates(&objectives) {
+		for _, severity := range methods.Severities {
+			if disableTicket && severity == methods.NotificationTicketSeverity {
+				continue
+			}
+			for _, rate := range alert.rates[severity] {
+				window, ok := rateWindow(rate, severity, budgetWindow)
+				if !ok {
+					continue
+				}
+
+				// burn rates of custom windows are computed, they are rounded to not show the noise
+				threshold := AlertThreshold{
+					Signal:   alert.signal,
+					LE:       alert.le,
+					Severity: severity,
+					Window:   window,
+					BurnRate: math.Round(rate.Multiplier*1e3) / 1e3,
+				}
+				if alert.signal == ErrorBlock {
+					threshold.Value = (100 - float64(objectives.Availability)) / 100 * threshold.BurnRate
+					threshold.Threshold = "error ratio > " + formatPercent(methods.Percent(threshold.Value*100))
+				} else {
+					threshold.Threshold = latencyThreshold(alert.targets, threshold.BurnRate)
+				}
+				thresholds = append(thresholds, threshold)
+			}
+		}
+	}
+
+	return thresholds
+}
+
+// latencyThreshold writes the conditions firing a latency alert on its targets
+func latencyThreshold(targets []methods.LatencyTarget, burnRate float64) string {
+	conditions := make([]string, 0, len(targets))
+	for _, target := range targets {
+		conditions = append(conditions, fmt.Sprintf("requests faster than %s < %s", target.LE,
+			formatPercent(methods.Percent(100-(100-float64(target.Target))*burnRate))))
+	}
+
+	return strings.Join(conditions, " or ")
+}
+
+// explain returns the value of the first candidate set
+func explain(field string, candidates ...candidate) Explanation {
+	for _, c := range candidates {
+		if c.set {
+			return Explanation{Field: field, Value: c.value(), Origin: c.origin}
+		}
+	}
+
+	return Explanation{Field: field}
+}
+
+func classOrigin(class *Class) string {
+	return fmt.Sprintf("class %q", class.Name)
+}
+
+func formatPercent(p methods.Percent) string {
+	return formatFloat(float64(p)) + "%"
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1e6)/1e6, 'f', -1, 64)
+}
+
+func formatLatency(o *Objectives) string {
+	if len(o.Latency) == 0 {
+		return "none"
+	}
+
+	targets := make([]string, 0, len(o.Latency))
+	for _, target := range o.Latency {
+		targets = append(targets, fmt.Sprintf("%s faster than %s", formatPercent(target.Target), target.LE))
+	}
+
+	return strings.Join(targets, ", ")
+}
+
+func formatSamples(config *samples.Config) string {
+	groups := make([]string, 0, len(config.Groups))
+	for _, group := range config.Groups {
+		groups = append(groups, fmt.Sprintf("%s every %s [%s]", group.Name, group.Interval, strings.Join(group.Buckets, ", ")))
+	}
+
+	return strings.Join(groups, ", ")
+}
+
+func formatQuantiles(quantiles []float64) string {
+	if len(quantiles) == 0 {
+		return "none"
+	}
+
+	names := make([]string, 0, len(quantiles))
+	for _, quantile := range quantiles {
+		names = append(names, QuantileName(quantile))
+	}
+
+	return strings.Join(names, ", ")
+}
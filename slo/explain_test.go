@@ -0,0 +1,277 @@
+package slo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/globocom/slo-generator/methods"
+)
+
+func TestSLOExplain(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+samples:
+  groups:
+    - name: short
+      interval: 30s
+      buckets: [5m, 1h]
+classes:
+  - name: BASE
+    objectives:
+      availability: 99
+      window: 28d
+      latency:
+        - le: 0.5
+          target: 99
+  - name: HIGH
+    extends: BASE
+    objectives:
+      availability: 99.9
+    latencyQuantileRecord:
+      quantiles: [0.9]
+slos:
+  - name: my-service
+    class: HIGH
+    objectives:
+      latency:
+        - le: 0.1
+          target: 95
+    latencyQuantileRecord:
+      expr: histogram_quantile($quantile, sum by (le) (rate(http_bucket[$window])))
+      quantiles: [0.99]
+  - name: other-service
+    class: LOW
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	explanations, err := spec.SLOS[0].Explain(spec.Classes)
+	assert.NoError(t, err)
+	assert.Equal(t, []Explanation{
+		{Field: "objectives.availability", Value: "99.9%", Origin: "class \"HIGH\""},
+		{Field: "objectives.latency", Value: "95% faster than 0.1", Origin: "SLO"},
+		{Field: "objectives.window", Value: "4w", Origin: "class \"BASE\""},
+		{Field: "samples", Value: "short every 30s [5m, 1h]", Origin: "specification"},
+		{Field: "latencyQuantileRecord.quantiles", Value: "p99", Origin: "SLO"},
+	}, explanations)
+	assert.Equal(t, "objectives.window = 4w (class \"BASE\")", explanations[2].String())
+
+	_, err = spec.SLOS[1].Explain(spec.Classes)
+	assert.EqualError(t, err, "SLO class \"LOW\" is not found")
+}
+
+func TestSLOAlertThresholds(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+classes:
+  - name: HIGH
+    objectives:
+      availability: 99.9
+      latency:
+        - le: 0.1
+          target: 95
+        - le: 1
+          target: 99
+    disableTicket: true
+slos:
+  - name: my-service
+    class: HIGH
+    errorRateRecord:
+      alertMethod: multi-window
+      expr: sum(rate(errors[$window])) / sum(rate(total[$window]))
+    latencyRecord:
+      alertMethod: simple
+      alertWindow: 1h
+      burnRate: 2
+      expr: sum(rate(bucket{le="$le"}[$window])) / sum(rate(total[$window]))
+      targets:
+        - le: 1
+          severity: ticket
+          alertWindow: 1d
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	sloClass, err := spec.Classes.FindClass("HIGH")
+	assert.NoError(t, err)
+
+	thresholds := spec.SLOS[0].AlertThresholds(sloClass, false)
+	lines := []string{}
+	for _, threshold := range thresholds {
+		lines = append(lines, threshold.String())
+	}
+	assert.Equal(t, []string{
+		"error page: 14.4x the budget over 1h (2% of the budget), fires when error ratio > 1.44%",
+		"error page: 6x the budget over 6h (5% of the budget), fires when error ratio > 0.6%",
+		"latency page: 2x the budget over 1h (0.277778% of the budget), fires when requests faster than 0.1 < 90%",
+	}, lines)
+
+	// without disableTicket in the class, tickets are alerted too
+	disableTicket := false
+	sloClass.DisableTicket = &disableTicket
+	thresholds = spec.SLOS[0].AlertThresholds(sloClass, false)
+	assert.Len(t, thresholds, 6)
+	assert.Equal(t, "1", thresholds[5].LE)
+	assert.Equal(t, methods.NotificationTicketSeverity, thresholds[5].Severity)
+	assert.Equal(t, "requests faster than 1 < 98%", thresholds[5].Threshold)
+}
+
+// TestSLOAlertThresholdsMatchAlerts checks that every threshold is the one of a condition
+// of the generated alerts, for every method and window setup
+func TestSLOAlertThresholdsMatchAlerts(t *testing.T) {
+	objectives := `
+    objectives:
+      availability: 99.5
+      window: 28d
+      latency:
+        - le: 0.1
+          target: 90
+        - le: 1
+          target: 99
+`
+	blocks := []string{`
+    errorRateRecord:
+      alertMethod: multi-window
+    latencyRecord:
+      alertMethod: multi-window
+`, `
+    errorRateRecord:
+      alertMethod: multi-window
+      shortWindow: false
+      windows:
+        - duration: 1h
+          consumption: 2%
+          notification: page
+        - duration: 4h
+          consumption: 5%
+          notification: page
+        - duration: 3d
+          consumption: 10%
+          notification: ticket
+    latencyRecord:
+      alertMethod: multi-window
+      severity: page
+      windows:
+        - duration: 2h
+          consumption: 3%
+          notification: page
+`, `
+    errorRateRecord:
+      alertMethod: simple
+      alertWindow: 6h
+      burnRate: 5
+      severity: ticket
+    latencyRecord:
+      alertMethod: simple
+      alertWindow: 1h
+      targets:
+        - le: 1
+          severity: ticket
+          alertWindow: 1d
+          burnRate: 2
+`, `
+    errorRateRecord:
+      alertMethod: simple
+      alertWindow: 1h
+    latencyRecord:
+      alertMethod: multi-window
+      alertPerTarget: true
+      targets:
+        - le: 0.1
+          windows:
+            - duration: 30m
+              consumption: 1%
+              notification: page
+`}
+
+	for _, block := range blocks {
+		spec, err := ParseSLOSpec([]byte("slos:\n  - name: my-service"+objectives+block), "slo.yml", true)
+		if !assert.NoError(t, err, block) || !assert.NoError(t, spec.Validate(), block) {
+			continue
+		}
+		slo := &spec.SLOS[0]
+
+		rules, err := slo.GenerateAlertRules(nil, false)
+		assert.NoError(t, err, block)
+		conditions := map[string]float64{}
+		for _, rule := range rules {
+			expr, err := parser.ParseExpr(rule.Expr.Value)
+			assert.NoError(t, err, rule.Expr.Value)
+			for key, value := range alertConditions(expr) {
+				conditions[rule.Labels["signal"]+" "+rule.Labels["severity"]+" "+key] = value
+			}
+		}
+
+		overridden := map[string]bool{}
+		for _, target := range slo.LatencyRecord.Targets {
+			overridden[target.LE] = true
+		}
+
+		thresholds := slo.AlertThresholds(nil, false)
+		assert.NotEmpty(t, thresholds, block)
+		for _, threshold := range thresholds {
+			prefix := fmt.Sprintf("%s %s %s", threshold.Signal, threshold.Severity, threshold.Window.Duration)
+			if threshold.Signal == ErrorBlock {
+				assert.InEpsilon(t, threshold.Value, conditions[prefix], 1e-3, "%s: %s", block, threshold)
+				continue
+			}
+
+			for _, target := range slo.Objectives.Latency {
+				if (threshold.LE == "" && !overridden[target.LE]) || threshold.LE == target.LE {
+					expected := 1 - (1-float64(target.Target)/100)*threshold.BurnRate
+					assert.InEpsilon(t, expected, conditions[prefix+" "+target.LE], 1e-3, "%s: %s", block, threshold)
+				}
+			}
+		}
+	}
+}
+
+// alertConditions returns the constant compared to each SLI recorded in an alert expression,
+// keyed by the window and the le of the SLI
+func alertConditions(expr parser.Expr) map[string]float64 {
+	conditions := map[string]float64{}
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		binary, ok := node.(*parser.BinaryExpr)
+		if !ok || (binary.Op != parser.GTR && binary.Op != parser.LSS) {
+			return nil
+		}
+		selector, ok := binary.LHS.(*parser.VectorSelector)
+		value, constant := constantValue(binary.RHS)
+		if !ok || !constant {
+			return nil
+		}
+
+		key := selector.Name[strings.LastIndex(selector.Name, "_")+1:]
+		for _, matcher := range selector.LabelMatchers {
+			if matcher.Name == "le" {
+				key += " " + matcher.Value
+			}
+		}
+		conditions[key] = value
+		return nil
+	})
+
+	return conditions
+}
+
+func constantValue(expr parser.Expr) (float64, bool) {
+	switch e := expr.(type) {
+	case *parser.NumberLiteral:
+		return e.Val, true
+	case *parser.ParenExpr:
+		return constantValue(e.Expr)
+	case *parser.BinaryExpr:
+		lhs, lok := constantValue(e.LHS)
+		rhs, rok := constantValue(e.RHS)
+		switch {
+		case !lok || !rok:
+			return 0, false
+		case e.Op == parser.MUL:
+			return lhs * rhs, true
+		case e.Op == parser.SUB:
+			return lhs - rhs, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,121 @@
+package slo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNativeHistogram is returned when the latency of a native histogram should be evaluated,
+// histogram_fraction is newer than the prometheus engine vendored
+var ErrNativeHistogram = errors.New("the latency SLI of native histograms can not be evaluated, histogram_fraction is not supported by the embedded prometheus engine")
+
+// LatencyHistogram builds the latency SLI from a prometheus histogram,
+// an alternative to writing the bucket ratio of latencyRecord by hand
+type LatencyHistogram struct {
+	Metric   string   `yaml:"metric"`   // name of the histogram, without the _bucket suffix
+	Selector string   `yaml:"selector"` // label matchers of the histogram, like {job="service-a"}
+	Buckets  []string `yaml:"buckets"`  // boundaries of the histogram, latency targets must be one of them
+	Native   bool     `yaml:"native"`   // native histograms are queried with histogram_fraction
+}
+
+// ComputeExpr returns the ratio of requests faster than le on the window
+func (h *LatencyHistogram) ComputeExpr(window, le string) string {
+	matchers := h.matchers()
+
+	if h.Native {
+		return fmt.Sprintf("histogram_fraction(0, %s, %s)", le, h.nativeRate(window))
+	}
+
+	bucketMatchers := append(matchers, fmt.Sprintf("le=%q", h.boundary(le)))
+	return fmt.Sprintf("sum(rate(%s[%s])) / sum(rate(%s[%s]))",
+		vectorSelector(h.Metric+"_bucket", bucketMatchers), window, vectorSelector(h.Metric+"_count", matchers), window)
+}
+
+// nativeRate returns the rate of the native histogram on the window
+func (h *LatencyHistogram) nativeRate(window string) string {
+	return fmt.Sprintf("sum(rate(%s[%s]))", vectorSelector(h.Metric, h.matchers()), window)
+}
+
+func vectorSelector(metric string, matchers []string) string {
+	if len(matchers) == 0 {
+		return metric
+	}
+
+	return fmt.Sprintf("%s{%s}", metric, strings.Join(matchers, ", "))
+}
+
+func (h *LatencyHistogram) matchers() []string {
+	selector := strings.TrimSpace(h.Selector)
+	selector = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(selector, "{"), "}"))
+	if selector == "" {
+		return nil
+	}
+
+	return []string{selector}
+}
+
+// boundary returns the bucket of the histogram with the same value of le, written as the
+// histogram exposes it (le="5" and le="5.0" are different series), or le when not declared
+func (h *LatencyHistogram) boundary(le string) string {
+	if bucket, ok := h.findBucket(le); ok {
+		return bucket
+	}
+
+	return le
+}
+
+// hasBoundary checks whether le is one of the declared buckets of the histogram
+func (h *LatencyHistogram) hasBoundary(le string) bool {
+	if len(h.Buckets) == 0 {
+		return true
+	}
+
+	_, ok := h.findBucket(le)
+	return ok
+}
+
+func (h *LatencyHistogram) findBucket(le string) (string, bool) {
+	value, err := strconv.ParseFloat(le, 64)
+	if err != nil {
+		return "", false
+	}
+
+	for _, bucket := range h.Buckets {
+		if bucketValue, err := strconv.ParseFloat(bucket, 64); err == nil && bucketValue == value {
+			return bucket, true
+		}
+	}
+
+	return "", false
+}
+
+// hasLatencySLI returns true when the latency SLI is recorded by the generator
+func (slo *SLO) hasLatencySLI() bool {
+	return slo.LatencyRecord.Expr != "" || slo.LatencyHistogram != nil
+}
+
+// ComputeLatencyExpr returns the latency ratio of le on the window, from latencyRecord or latencyHistogram
+func (slo *SLO) ComputeLatencyExpr(window, le string) string {
+	if slo.LatencyRecord.Expr == "" && slo.LatencyHistogram != nil {
+		return slo.LatencyHistogram.ComputeExpr(window, le)
+	}
+
+	return slo.LatencyRecord.ComputeExpr(window, le)
+}
+
+// HasNativeHistogram returns true when the latency SLI is recorded from a native histogram
+func (slo *SLO) HasNativeHistogram() bool {
+	return slo.LatencyRecord.Expr == "" && slo.LatencyHistogram != nil && slo.LatencyHistogram.Native
+}
+
+// checkedLatencyExpr returns the part of the latency expression parsed on generation: the parser
+// vendored does not know histogram_fraction, so only the rate of native histograms is parsed
+func (slo *SLO) checkedLatencyExpr(window, le string) string {
+	if slo.HasNativeHistogram() {
+		return slo.LatencyHistogram.nativeRate(window)
+	}
+
+	return slo.ComputeLatencyExpr(window, le)
+}
@@ -0,0 +1,103 @@
+package slo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/globocom/slo-generator/methods"
+	"github.com/globocom/slo-generator/samples"
+)
+
+func TestSLOGenerateGroupRulesWithLatencyHistogram(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Latency: []methods.LatencyTarget{
+				{LE: "0.1", Target: 95},
+				{LE: "5", Target: 99},
+			},
+		},
+		LatencyHistogram: &LatencyHistogram{
+			Metric:   "http_request_duration_seconds",
+			Selector: `{job="service-a"}`,
+			Buckets:  []string{"0.1", "0.5", "5.0"},
+		},
+		Samples: &samples.Config{
+			Groups: []samples.Sample{
+				{Name: "short", Interval: "30s", Buckets: []string{"5m"}},
+			},
+		},
+	}
+
+	groupRules, err := slo.GenerateGroupRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, groupRules, 1)
+	assert.Len(t, groupRules[0].Rules, 2)
+
+	assert.Equal(t, "slo:service_latency:ratio_rate_5m", groupRules[0].Rules[0].Record.Value)
+	assert.Equal(t, `sum(rate(http_request_duration_seconds_bucket{job="service-a", le="0.1"}[5m])) / sum(rate(http_request_duration_seconds_count{job="service-a"}[5m]))`, groupRules[0].Rules[0].Expr.Value)
+	assert.Equal(t, map[string]string{"service": "my-service", "le": "0.1"}, groupRules[0].Rules[0].Labels)
+
+	// the boundary is written as the histogram exposes it
+	assert.Equal(t, `sum(rate(http_request_duration_seconds_bucket{job="service-a", le="5.0"}[5m])) / sum(rate(http_request_duration_seconds_count{job="service-a"}[5m]))`, groupRules[0].Rules[1].Expr.Value)
+	assert.Equal(t, map[string]string{"service": "my-service", "le": "5"}, groupRules[0].Rules[1].Labels)
+}
+
+func TestSLOGenerateGroupRulesWithNativeHistogram(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Latency: []methods.LatencyTarget{
+				{LE: "0.25", Target: 95},
+			},
+		},
+		LatencyHistogram: &LatencyHistogram{
+			Metric: "http_request_duration_seconds",
+			Native: true,
+		},
+		Samples: &samples.Config{
+			Groups: []samples.Sample{
+				{Name: "short", Interval: "30s", Buckets: []string{"5m"}},
+			},
+		},
+	}
+
+	groupRules, err := slo.GenerateGroupRules(nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "histogram_fraction(0, 0.25, sum(rate(http_request_duration_seconds[5m])))", groupRules[0].Rules[0].Expr.Value)
+}
+
+func TestValidateLatencyHistogram(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+slos:
+  - name: my-service
+    objectives:
+      latency:
+        - le: 0.2
+          target: 95
+        - le: 1
+          target: 99
+    latencyRecord:
+      expr: sum(rate(http_bucket{le="$le"}[$window]))/sum(rate(http_total[$window]))
+    latencyHistogram:
+      metric: http_request_duration_seconds
+      selector: '{job=}'
+      buckets: [0.1, 0.5, 1.0, slow]
+  - name: other-service
+    latencyHistogram:
+      metric: http-duration
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	err = spec.Validate()
+	if assert.IsType(t, ValidationErrors{}, err) {
+		messages := errorMessages(err.(ValidationErrors))
+		assert.Len(t, messages, 5)
+		assert.Equal(t, "slo.yml:11:13: SLO \"my-service\": latencyRecord.expr: expr can not be used together with latencyHistogram", messages[0])
+		assert.Contains(t, messages[1], "slo.yml:14:17: SLO \"my-service\": latencyHistogram.selector: invalid selector:")
+		assert.Equal(t, "slo.yml:15:16: SLO \"my-service\": latencyHistogram.buckets: latency target le 0.2 is not a boundary of the histogram, boundaries: 0.1, 0.5, 1.0, slow", messages[2])
+		assert.Equal(t, "slo.yml:15:32: SLO \"my-service\": latencyHistogram.buckets[3]: bucket must be a numeric histogram boundary, got \"slow\"", messages[3])
+		assert.Equal(t, "slo.yml:18:15: SLO \"other-service\": latencyHistogram.metric: metric \"http-duration\" is not a valid metric name", messages[4])
+	}
+}
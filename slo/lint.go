@@ -0,0 +1,59 @@
+package slo
+
+import (
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// minBudgetWindow is the shortest window recommended to compute error budgets
+const minBudgetWindow = model.Duration(28 * 24 * time.Hour)
+
+// Lint checks the SLO, with the policy of its class, against the practices recommended by the
+// SRE workbook. Unlike validation problems, rules can still be generated
+func (slo *SLO) Lint(sloClass *Class) []Warning {
+	slo = slo.withClass(sloClass)
+	objectives := slo.objectives(sloClass)
+
+	var warnings []Warning
+	warn := func(record, msg string) {
+		warnings = append(warnings, Warning{SLO: slo.Name, Record: record, Msg: msg})
+	}
+
+	blocks := []struct {
+		name     string
+		block    *ExprBlock
+		recorded bool
+	}{
+		{name: ErrorBlock, block: &slo.ErrorRateRecord, recorded: slo.ErrorRateRecord.HasSLI()},
+		{name: LatencyBlock, block: &slo.LatencyRecord, recorded: len(objectives.Latency) > 0 && (slo.LatencyRecord.HasSLI() || slo.LatencyHistogram != nil)},
+	}
+
+	alerted := false
+	for _, b := range blocks {
+		switch b.block.AlertMethod {
+		case "":
+			if b.recorded {
+				warn(b.name, "the SLI is recorded but not alerted, set alertMethod")
+			}
+		case "simple":
+			warn(b.name, "simple alerts either have a poor precision or a long reset time, multi-window alerts are recommended")
+		case "multi-window":
+			if !b.block.GetShortWindow() {
+				warn(b.name, "without short windows, alerts keep firing long after the budget stops burning")
+			}
+		}
+		alerted = alerted || b.block.AlertMethod != ""
+	}
+
+	hasRunbook := slo.Annotations["runbook_url"] != "" || (slo.AlertAnnotations != nil && slo.AlertAnnotations.RunbookURL != "")
+	if alerted && !hasRunbook {
+		warn("", "alerts have no runbook_url annotation, set it in annotations or in alertAnnotations.runbookURL")
+	}
+
+	if objectives.budgetWindow() < minBudgetWindow {
+		warn("", "objectives.window is shorter than 4 weeks, the budget may not cover a whole cycle of traffic")
+	}
+
+	return warnings
+}
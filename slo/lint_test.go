@@ -0,0 +1,55 @@
+package slo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSLOLint(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+classes:
+  - name: HIGH
+    objectives:
+      availability: 99.9
+      window: 7d
+      latency:
+        - le: 0.1
+          target: 95
+    annotations:
+      runbook_url: https://wiki.ops/runbooks/high
+slos:
+  - name: my-service
+    errorRateRecord:
+      alertMethod: simple
+      alertWindow: 1h
+      expr: sum(rate(errors[$window])) / sum(rate(total[$window]))
+    latencyRecord:
+      alertMethod: multi-window
+      shortWindow: false
+      expr: sum(rate(bucket{le="$le"}[$window])) / sum(rate(total[$window]))
+  - name: other-service
+    class: HIGH
+    errorRateRecord:
+      alertMethod: multi-window
+      expr: sum(rate(errors[$window])) / sum(rate(total[$window]))
+    latencyRecord:
+      expr: sum(rate(bucket{le="$le"}[$window])) / sum(rate(total[$window]))
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Warning{
+		{SLO: "my-service", Record: ErrorBlock, Msg: "simple alerts either have a poor precision or a long reset time, multi-window alerts are recommended"},
+		{SLO: "my-service", Record: LatencyBlock, Msg: "without short windows, alerts keep firing long after the budget stops burning"},
+		{SLO: "my-service", Msg: "alerts have no runbook_url annotation, set it in annotations or in alertAnnotations.runbookURL"},
+	}, spec.SLOS[0].Lint(nil))
+
+	sloClass, err := spec.Classes.FindClass("HIGH")
+	assert.NoError(t, err)
+	warnings := spec.SLOS[1].Lint(sloClass)
+	assert.Equal(t, []Warning{
+		{SLO: "other-service", Record: LatencyBlock, Msg: "the SLI is recorded but not alerted, set alertMethod"},
+		{SLO: "other-service", Msg: "objectives.window is shorter than 4 weeks, the budget may not cover a whole cycle of traffic"},
+	}, warnings)
+	assert.Equal(t, `SLO "other-service": objectives.window is shorter than 4 weeks, the budget may not cover a whole cycle of traffic`, warnings[1].String())
+}
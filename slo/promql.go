@@ -0,0 +1,140 @@
+package slo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// ExprError is returned when a generated expression is not a valid PromQL
+type ExprError struct {
+	Window       string
+	Substitution string
+	Expr         string
+	Err          error
+}
+
+func (e *ExprError) Error() string {
+	msg := "invalid PromQL"
+	if e.Window != "" {
+		msg += " for window " + e.Window
+	}
+	if e.Substitution != "" {
+		msg += " (" + e.Substitution + ")"
+	}
+
+	return fmt.Sprintf("%s: %s, expr: %s", msg, e.Err.Error(), e.Expr)
+}
+
+func (e *ExprError) Unwrap() error {
+	return e.Err
+}
+
+// checkExpr parses a generated expression
+func checkExpr(expr, window, substitution string) error {
+	if _, err := parser.ParseExpr(expr); err != nil {
+		return &ExprError{
+			Window:       window,
+			Substitution: substitution,
+			Expr:         strings.TrimSpace(expr),
+			Err:          err,
+		}
+	}
+
+	return nil
+}
+
+// checkAlertExprs parses every expression built by an alert method
+func checkAlertExprs(rules []rulefmt.Rule) error {
+	for _, rule := range rules {
+		if err := checkExpr(rule.Expr, "", "alert "+rule.Alert); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Warning is a problem found in a SLO that does not prevent rules to be generated
+type Warning struct {
+	SLO    string
+	Record string // empty when the problem is not in a record
+	Msg    string
+}
+
+func (w Warning) String() string {
+	if w.Record == "" {
+		return fmt.Sprintf("SLO %q: %s", w.SLO, w.Msg)
+	}
+
+	return fmt.Sprintf("SLO %q, %s record: %s", w.SLO, w.Record, w.Msg)
+}
+
+// placeholderRegexp matches $name and ${name}, but not the $1 references of label_replace
+var placeholderRegexp = regexp.MustCompile(`\$(\{(?:[a-zA-Z_]\w*)?\}|[a-zA-Z_]\w*)`)
+
+// Warnings checks the expressions given by user looking for common mistakes:
+// placeholders that were not substituted and SLIs that do not return a ratio
+func (slo *SLO) Warnings() []Warning {
+	var warnings []Warning
+
+	blocks := []struct {
+		name  string
+		block *ExprBlock
+		expr  string
+		ratio bool
+	}{
+		{name: TrafficBlock, block: &slo.TrafficRateRecord, expr: slo.TrafficRateRecord.ComputeExpr("5m", "")},
+		{name: ErrorBlock, block: &slo.ErrorRateRecord, expr: slo.ErrorRateRecord.ComputeExpr("5m", ""), ratio: true},
+		{name: LatencyBlock, block: &slo.LatencyRecord, expr: slo.LatencyRecord.ComputeExpr("5m", "0.1"), ratio: true},
+		{name: LatencyQuantileBlock, block: &slo.LatencyQuantileRecord, expr: slo.LatencyQuantileRecord.ComputeQuantile("5m", 0.99)},
+	}
+
+	for _, b := range blocks {
+		if b.block.Expr == "" {
+			continue
+		}
+
+		warn := func(format string, args ...interface{}) {
+			warnings = append(warnings, Warning{SLO: slo.Name, Record: b.name, Msg: fmt.Sprintf(format, args...)})
+		}
+
+		if !strings.Contains(b.block.Expr, "$window") {
+			warn("expr has no $window placeholder, every window will record the same value")
+		}
+
+		for _, placeholder := range placeholderRegexp.FindAllString(b.expr, -1) {
+			warn("placeholder %s was left unsubstituted", placeholder)
+		}
+
+		if !b.ratio {
+			continue
+		}
+
+		expr, err := parser.ParseExpr(b.expr)
+		if err != nil {
+			// invalid expressions are reported as errors on generation
+			continue
+		}
+		if !hasDivision(expr) {
+			warn("expr does not look like a ratio, it has no division")
+		}
+	}
+
+	return warnings
+}
+
+func hasDivision(expr parser.Expr) bool {
+	found := false
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if binary, ok := node.(*parser.BinaryExpr); ok && binary.Op == parser.DIV {
+			found = true
+		}
+		return nil
+	})
+
+	return found
+}
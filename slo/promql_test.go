@@ -0,0 +1,69 @@
+package slo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/globocom/slo-generator/methods"
+)
+
+func TestSLOGenerateGroupRulesWithInvalidPromQL(t *testing.T) {
+	slo := &SLO{
+		Name: "my-team.my-service.payment",
+		Objectives: Objectives{
+			Availability: 99.9,
+			Latency: []methods.LatencyTarget{
+				{LE: "0.1", Target: 90},
+			},
+		},
+		LatencyRecord: ExprBlock{
+			Expr: "sum(rate(http_bucket{le=\"$le\"}[$window]))/sum(rate(http_total[$window])",
+		},
+	}
+
+	groupRules, err := slo.GenerateGroupRules(nil, false)
+	assert.Nil(t, groupRules)
+	assert.EqualError(t, err, "could not generate SLO \"my-team.my-service.payment\", latency record, field expr: "+
+		"invalid PromQL for window 5m ($window=5m, $le=0.1): 1:62: parse error: unclosed left parenthesis, "+
+		"expr: sum(rate(http_bucket{le=\"0.1\"}[5m]))/sum(rate(http_total[5m])")
+
+	var exprErr *ExprError
+	if assert.True(t, errors.As(err, &exprErr)) {
+		assert.Equal(t, "5m", exprErr.Window)
+		assert.Equal(t, "$window=5m, $le=0.1", exprErr.Substitution)
+	}
+}
+
+func TestSLOWarnings(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		TrafficRateRecord: ExprBlock{
+			Expr: "sum(rate(http_total[5m]))",
+		},
+		ErrorRateRecord: ExprBlock{
+			Expr: "sum(rate(http_errors{job=\"${window}\"}[$window]))",
+		},
+		LatencyRecord: ExprBlock{
+			Expr: "sum(rate(http_bucket{le=\"$le\"}[$window]))/sum(rate(http_total[$window]))",
+		},
+	}
+
+	assert.Equal(t, []Warning{
+		{SLO: "my-service", Record: TrafficBlock, Msg: "expr has no $window placeholder, every window will record the same value"},
+		{SLO: "my-service", Record: ErrorBlock, Msg: "placeholder ${window} was left unsubstituted"},
+		{SLO: "my-service", Record: ErrorBlock, Msg: "expr does not look like a ratio, it has no division"},
+	}, slo.Warnings())
+}
+
+func TestSLOWarningsIgnoreLabelReplaceReferences(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		ErrorRateRecord: ExprBlock{
+			Expr: "sum(label_replace(rate(http_errors[$window]), \"code\", \"$1${2}\", \"status\", \"(.)(..)\")) / sum(rate(http_total[$window]))",
+		},
+	}
+
+	assert.Empty(t, slo.Warnings())
+}
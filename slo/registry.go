@@ -0,0 +1,167 @@
+package slo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ExpandPaths returns the files of the given paths: files are kept as they are, directories are
+// replaced by their .yml and .yaml files, searched recursively, and globs by the files they match,
+// both sorted by name
+func ExpandPaths(paths []string) ([]string, error) {
+	var files []string
+
+	for _, path := range paths {
+		if strings.ContainsAny(path, "*?[") {
+			matches, err := filepath.Glob(path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%s: no file matches the pattern", path)
+			}
+			sort.Strings(matches)
+			files = append(files, matches...)
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		// subdirectories are walked in lexical order
+		err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			ext := filepath.Ext(file)
+			if !info.IsDir() && (ext == ".yml" || ext == ".yaml") {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// ReadClasses reads SLO classes from files, directories and globs, like the classes shared by a
+// platform team and the ones of a product team. A class can only be defined once among all files
+func ReadClasses(paths []string, strict bool) (Classes, error) {
+	files, err := ExpandPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	classes := Classes{}
+	for _, file := range files {
+		definition, err := ReadClassesDefinition(file, strict)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, class := range definition.Classes {
+			if previous := classes.find(class.Name); previous != nil {
+				return nil, fmt.Errorf("class %q is defined more than once, in %s and %s", class.Name, previous.location(), class.location())
+			}
+			classes = append(classes, class)
+		}
+	}
+
+	return classes, nil
+}
+
+// ReadSLOSpecs reads SLO specifications from files, directories and globs, every document of a
+// YAML stream is a specification. A SLO can only be defined once among all files
+func ReadSLOSpecs(paths []string, strict bool) ([]*SLOSpec, error) {
+	files, err := ExpandPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []*SLOSpec
+	slos := map[string]*SLO{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		fileSpecs, err := ParseSLOSpecs(content, file, strict)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, spec := range fileSpecs {
+			for i := range spec.SLOS {
+				slo := &spec.SLOS[i]
+				if previous, ok := slos[slo.Name]; ok {
+					return nil, fmt.Errorf("SLO %q is defined more than once, in %s and %s", slo.Name, previous.location(), slo.location())
+				}
+				slos[slo.Name] = slo
+			}
+		}
+		specs = append(specs, fileSpecs...)
+	}
+
+	return specs, nil
+}
+
+// AddSharedClasses adds classes shared by other files to the specification. Classes of the
+// specification shadow the shared ones with the same name, returning a warning for each one
+func (spec *SLOSpec) AddSharedClasses(shared Classes) []string {
+	var warnings []string
+
+	for _, class := range shared {
+		if local := spec.Classes.find(class.Name); local != nil {
+			warnings = append(warnings, fmt.Sprintf("class %q of %s shadows the one of %s", class.Name, local.location(), class.location()))
+			continue
+		}
+		spec.Classes = append(spec.Classes, class)
+	}
+
+	return warnings
+}
+
+// location returns where the class was declared, like slo.yml:12
+func (class *Class) location() string {
+	if class.source.node == nil {
+		return class.source.file
+	}
+
+	return fmt.Sprintf("%s:%d", class.source.file, class.source.node.Line)
+}
+
+func (slo *SLO) location() string {
+	if slo.source.node == nil {
+		return slo.source.file
+	}
+
+	return fmt.Sprintf("%s:%d", slo.source.file, slo.source.node.Line)
+}
+
+// File returns the file where the SLO was declared
+func (slo *SLO) File() string {
+	return slo.source.file
+}
+
+// Team returns the team label of the SLO or of its class, empty when not set
+func (slo *SLO) Team(sloClass *Class) string {
+	rendered, err := slo.renderLabels(sloClass)
+	if err != nil {
+		return slo.withClass(sloClass).Labels["team"]
+	}
+
+	return rendered.Labels["team"]
+}
@@ -0,0 +1,187 @@
+package slo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	return dir
+}
+
+func classNames(classes Classes) []string {
+	names := []string{}
+	for _, class := range classes {
+		names = append(names, class.Name)
+	}
+
+	return names
+}
+
+func TestReadClasses(t *testing.T) {
+	org := writeFiles(t, map[string]string{
+		"org.yml": `
+classes:
+  - name: HIGH
+    objectives:
+      availability: 99.9
+`,
+		"notes.txt": "not a class file",
+	})
+	team := writeFiles(t, map[string]string{
+		"team-b.yaml": `
+classes:
+  - name: CRITICAL
+    extends: HIGH
+    objectives:
+      availability: 99.99
+`,
+		"team-a.yml": `
+classes:
+  - name: LOW
+    objectives:
+      availability: 99
+`,
+	})
+
+	classes, err := ReadClasses([]string{org, filepath.Join(team, "team-*")}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"HIGH", "LOW", "CRITICAL"}, classNames(classes))
+
+	class, err := classes.FindClass("CRITICAL")
+	assert.NoError(t, err)
+	assert.Equal(t, 99.99, float64(class.Objectives.Availability))
+}
+
+func TestReadClassesDuplicated(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yml": `
+classes:
+  - name: HIGH
+    objectives:
+      availability: 99.9
+`,
+		"b.yml": `
+classes:
+  - name: LOW
+    objectives:
+      availability: 99
+  - name: HIGH
+    objectives:
+      availability: 99.5
+`,
+	})
+
+	_, err := ReadClasses([]string{dir}, true)
+	assert.EqualError(t, err, `class "HIGH" is defined more than once, in `+filepath.Join(dir, "a.yml")+`:3 and `+filepath.Join(dir, "b.yml")+`:6`)
+}
+
+func TestReadClassesNotFound(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := ReadClasses([]string{filepath.Join(dir, "*.yml")}, true)
+	assert.EqualError(t, err, filepath.Join(dir, "*.yml")+": no file matches the pattern")
+}
+
+func TestAddSharedClasses(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+classes:
+  - name: HIGH
+    objectives:
+      availability: 99.5
+slos:
+  - name: my-service
+    class: HIGH
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	shared, err := ParseClassesDefinition([]byte(`
+classes:
+  - name: HIGH
+    objectives:
+      availability: 99.9
+  - name: LOW
+    objectives:
+      availability: 99
+`), "org.yml", true)
+	assert.NoError(t, err)
+
+	warnings := spec.AddSharedClasses(shared.Classes)
+	assert.Equal(t, []string{`class "HIGH" of slo.yml:3 shadows the one of org.yml:3`}, warnings)
+	assert.Equal(t, []string{"HIGH", "LOW"}, classNames(spec.Classes))
+
+	class, err := spec.Classes.FindClass("HIGH")
+	assert.NoError(t, err)
+	assert.Equal(t, 99.5, float64(class.Objectives.Availability))
+}
+
+func TestReadSLOSpecs(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"payments.yml": `
+slos:
+  - name: payments
+    objectives:
+      availability: 99.9
+`,
+	})
+	sub := filepath.Join(dir, "checkout")
+	assert.NoError(t, os.Mkdir(sub, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(sub, "slo.yaml"), []byte(`
+slos:
+  - name: cart
+---
+classes:
+  - name: HIGH
+slos:
+  - name: checkout
+    class: HIGH
+`), 0644))
+
+	specs, err := ReadSLOSpecs([]string{dir}, true)
+	assert.NoError(t, err)
+	assert.Len(t, specs, 3)
+	assert.Equal(t, "cart", specs[0].SLOS[0].Name)
+	assert.Equal(t, "checkout", specs[1].SLOS[0].Name)
+	assert.Equal(t, []string{"HIGH"}, classNames(specs[1].Classes))
+	assert.Equal(t, filepath.Join(sub, "slo.yaml"), specs[1].SLOS[0].File())
+	assert.Equal(t, filepath.Join(sub, "slo.yaml")+":8", specs[1].SLOS[0].location())
+	assert.Equal(t, "payments", specs[2].SLOS[0].Name)
+}
+
+func TestReadSLOSpecsDuplicated(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yml": `
+slos:
+  - name: payments
+`,
+		"b.yml": `
+slos:
+  - name: cart
+---
+slos:
+  - name: payments
+`,
+	})
+
+	_, err := ReadSLOSpecs([]string{filepath.Join(dir, "*.yml")}, true)
+	assert.EqualError(t, err, `SLO "payments" is defined more than once, in `+filepath.Join(dir, "a.yml")+`:3 and `+filepath.Join(dir, "b.yml")+`:6`)
+}
+
+func TestSLOTeam(t *testing.T) {
+	slo := &SLO{Name: "payments", Labels: map[string]string{"team": "payments"}}
+	assert.Equal(t, "payments", slo.Team(nil))
+	assert.Equal(t, "payments", slo.Team(&Class{Labels: map[string]string{"team": "platform"}}))
+
+	slo = &SLO{Name: "cart"}
+	assert.Equal(t, "", slo.Team(nil))
+	assert.Equal(t, "platform", slo.Team(&Class{Labels: map[string]string{"team": "platform"}}))
+}
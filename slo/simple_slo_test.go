@@ -1,6 +1,7 @@
 package slo
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -35,6 +36,7 @@ func TestSimpleSLOGenerateAlertRules(t *testing.T) {
 			Expr:        "kk",
 		},
 		LatencyRecord: ExprBlock{
+			BurnRate:    3,
 			AlertMethod: "simple",
 			AlertWindow: "30m",
 			AlertWait:   "2m",
@@ -50,7 +52,8 @@ func TestSimpleSLOGenerateAlertRules(t *testing.T) {
 		},
 	}
 
-	alertRules := slo.GenerateAlertRules(nil, false)
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
 	assert.Len(t, alertRules, 2)
 
 	assert.Equal(t, ruleNode(rulefmt.Rule{
@@ -67,7 +70,7 @@ func TestSimpleSLOGenerateAlertRules(t *testing.T) {
 
 	assert.Equal(t, ruleNode(rulefmt.Rule{
 		Alert: "slo:my-team.my-service.payment.latency.page",
-		Expr:  "slo:service_latency:ratio_rate_30m{le=\"0.1\", service=\"my-team.my-service.payment\"} < 2 * 0.95 or slo:service_latency:ratio_rate_30m{le=\"0.5\", service=\"my-team.my-service.payment\"} < 2 * 0.99",
+		Expr:  "slo:service_latency:ratio_rate_30m{le=\"0.1\", service=\"my-team.my-service.payment\"} < 1 - 3 * 0.05 or slo:service_latency:ratio_rate_30m{le=\"0.5\", service=\"my-team.my-service.payment\"} < 1 - 3 * 0.01",
 		Labels: map[string]string{
 			"channel":  "my-channel",
 			"severity": "page",
@@ -116,7 +119,14 @@ func TestSimpleSLOInvalid(t *testing.T) {
 		},
 	}
 
-	assert.PanicsWithValue(t, "Could not generate alert, err: Sample 22m is not a valid sample, valid samples: 5m,30m,1h,2h,6h,1d,3d", func() {
-		slo.GenerateAlertRules(nil, false)
-	})
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.Nil(t, alertRules)
+	assert.EqualError(t, err, "could not generate SLO \"my-team.my-service.payment\", error record, field alertWindow: Sample 22m is not a valid sample, valid samples: 5m,30m,1h,2h,6h,1d,3d")
+
+	var generateErr *GenerateError
+	if assert.True(t, errors.As(err, &generateErr)) {
+		assert.Equal(t, "my-team.my-service.payment", generateErr.SLO)
+		assert.Equal(t, ErrorBlock, generateErr.Record)
+		assert.Equal(t, "alertWindow", generateErr.Field)
+	}
 }
@@ -2,7 +2,8 @@ package slo
 
 import (
 	"fmt"
-	"log"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,38 +14,38 @@ import (
 	"github.com/prometheus/prometheus/pkg/rulefmt"
 )
 
-var quantiles = []struct {
-	name     string
-	quantile float64
-}{
-	{
-		name:     "p50",
-		quantile: 0.5,
-	},
-	{
-		name:     "p95",
-		quantile: 0.95,
-	},
-	{
-		name:     "p99",
-		quantile: 0.99,
-	},
-}
+// DefaultQuantiles are recorded by latencyQuantileRecord when SLOs and classes do not define quantiles
+var DefaultQuantiles = []float64{0.5, 0.95, 0.99}
 
 type SLOSpec struct {
-	SLOS    []SLO   `yaml:"slos"`
-	Classes Classes `yaml:"classes"`
+	SLOS    []SLO           `yaml:"slos"`
+	Classes Classes         `yaml:"classes"`
+	Samples *samples.Config `yaml:"samples"`
+
+	AlertAnnotations *AlertAnnotations `yaml:"alertAnnotations"`
+
+	source source
 }
 
 type ExprBlock struct {
-	AlertMethod string           `yaml:"alertMethod"`
-	AlertWindow string           `yaml:"alertWindow"`
-	BurnRate    float64          `yaml:"burnRate"`
-	AlertWait   string           `yaml:"alertWait"`
-	Windows     []methods.Window `yaml:"windows"`
-	ShortWindow *bool            `yaml:"shortWindow"`
-	Buckets     []string         `yaml:"buckets"` // used to define buckets of histogram when using latency expression
-	Expr        string           `yaml:"expr"`
+	AlertMethod string                       `yaml:"alertMethod"`
+	AlertWindow string                       `yaml:"alertWindow"`
+	BurnRate    float64                      `yaml:"burnRate"`
+	AlertWait   string                       `yaml:"alertWait"`
+	Severity    methods.NotificationSeverity `yaml:"severity"`
+	Windows     []methods.Window             `yaml:"windows"`
+	ShortWindow *bool                        `yaml:"shortWindow"`
+	Targets     []LatencyAlertTarget         `yaml:"targets"`        // used to alert each latency target with its own options
+	PerTarget   *bool                        `yaml:"alertPerTarget"` // used to generate one alert by latency target
+	Buckets     []string                     `yaml:"buckets"`        // used to define buckets of histogram when using latency expression
+	Quantiles   []float64                    `yaml:"quantiles"`      // used to define quantiles recorded by latency quantile expression
+	Expr        string                       `yaml:"expr"`
+
+	// event based SLIs, an alternative to expr where the ratio is built from
+	// selectors of counters: totalEvents and one of goodEvents or badEvents
+	GoodEvents  string `yaml:"goodEvents"`
+	BadEvents   string `yaml:"badEvents"`
+	TotalEvents string `yaml:"totalEvents"`
 }
 
 func (block *ExprBlock) GetShortWindow() bool {
@@ -57,6 +58,35 @@ func (block *ExprBlock) GetShortWindow() bool {
 	return *block.ShortWindow
 }
 
+// GetPerTarget returns whether one alert is generated by latency target, false by default
+func (block *ExprBlock) GetPerTarget() bool {
+	if block.PerTarget == nil {
+		return false
+	}
+
+	return *block.PerTarget
+}
+
+// IsEventBased returns true when the SLI is built from counters of events instead of expr
+func (block *ExprBlock) IsEventBased() bool {
+	return block.GoodEvents != "" || block.BadEvents != "" || block.TotalEvents != ""
+}
+
+// HasSLI returns true when the block records a SLI, by expr or by events
+func (block *ExprBlock) HasSLI() bool {
+	return block.Expr != "" || block.IsEventBased()
+}
+
+// ComputeEvents returns the rate of bad events and the rate of all events on the window
+func (block *ExprBlock) ComputeEvents(window string) (string, string) {
+	total := fmt.Sprintf("sum(rate(%s[%s]))", block.TotalEvents, window)
+	if block.BadEvents != "" {
+		return fmt.Sprintf("sum(rate(%s[%s]))", block.BadEvents, window), total
+	}
+
+	return fmt.Sprintf("%s - sum(rate(%s[%s]))", total, block.GoodEvents, window), total
+}
+
 func (block *ExprBlock) ComputeExpr(window, le string) string {
 	replacer := strings.NewReplacer("$window", window, "$le", le)
 	return replacer.Replace(block.Expr)
@@ -74,16 +104,30 @@ type SLO struct {
 
 	HonorLabels bool `yaml:"honorLabels"`
 
+	// ObjectiveMetrics makes alerts compare SLIs with the objectives recorded by budget rules
+	ObjectiveMetrics bool `yaml:"objectiveMetrics"`
+
 	TrafficRateRecord     ExprBlock         `yaml:"trafficRateRecord"`
 	ErrorRateRecord       ExprBlock         `yaml:"errorRateRecord"`
 	LatencyRecord         ExprBlock         `yaml:"latencyRecord"`
 	LatencyQuantileRecord ExprBlock         `yaml:"latencyQuantileRecord"`
+	LatencyHistogram      *LatencyHistogram `yaml:"latencyHistogram"`
 	Labels                map[string]string `yaml:"labels"`
 	Annotations           map[string]string `yaml:"annotations"`
+
+	// Samples are the windows recorded for the SLO, filled with the samples
+	// of the specification, default samples are used when nil
+	Samples *samples.Config `yaml:"-"`
+
+	// AlertAnnotations are filled with the alertAnnotations of the specification,
+	// annotations are only generated when they are set
+	AlertAnnotations *AlertAnnotations `yaml:"-"`
+
+	source source
 }
 
 type Objectives struct {
-	Availability float64                 `yaml:"availability"`
+	Availability methods.Percent         `yaml:"availability"`
 	Latency      []methods.LatencyTarget `yaml:"latency"`
 	Window       model.Duration          `yaml:"window"`
 }
@@ -100,32 +144,84 @@ func (o *Objectives) LatencyBuckets() []string {
 	return latencyBuckets
 }
 
-func (slo *SLO) GenerateAlertRules(sloClass *Class, disableTicket bool) []rulefmt.RuleNode {
-	objectives := slo.Objectives
-	if sloClass != nil {
-		objectives = sloClass.Objectives
+// objectives returns the objectives of the SLO, fields not set by the SLO are taken from its class
+func (slo *SLO) objectives(sloClass *Class) Objectives {
+	if sloClass == nil {
+		return slo.Objectives
+	}
+
+	return slo.Objectives.override(sloClass.Objectives)
+}
+
+// samples returns the windows recorded for the SLO, samples of the class take precedence
+func (slo *SLO) samples(sloClass *Class) *samples.Config {
+	if sloClass != nil && sloClass.Samples != nil {
+		return sloClass.Samples
+	}
+	if slo.Samples != nil {
+		return slo.Samples
+	}
+
+	return samples.DefaultConfig
+}
+
+// quantiles returns the quantiles recorded for the SLO, the ones of the SLO, then of its class, then the defaults.
+// An empty list records no quantile.
+func (slo *SLO) quantiles(sloClass *Class) []float64 {
+	if slo.LatencyQuantileRecord.Quantiles != nil {
+		return slo.LatencyQuantileRecord.Quantiles
+	}
+	if sloClass != nil && sloClass.LatencyQuantileRecord != nil && sloClass.LatencyQuantileRecord.Quantiles != nil {
+		return sloClass.LatencyQuantileRecord.Quantiles
+	}
+
+	return DefaultQuantiles
+}
+
+// QuantileName returns the name of a quantile used in records: p50 for 0.5, p999 for 0.999.
+// Percentiles below 10 are written with two digits, like p05 for 0.05, so p099 (0.099) and p99 (0.99) differ
+func QuantileName(quantile float64) string {
+	percentile := math.Round(quantile*100*1e6) / 1e6
+	name := strconv.FormatFloat(percentile, 'f', -1, 64)
+	if percentile < 10 {
+		name = "0" + name
+	}
+
+	return "p" + strings.Replace(name, ".", "", 1)
+}
+
+func (slo *SLO) GenerateAlertRules(sloClass *Class, disableTicket bool) ([]rulefmt.RuleNode, error) {
+	slo, err := slo.renderLabels(sloClass)
+	if err != nil {
+		return nil, err
 	}
+	disableTicket = sloClass.ticketsDisabled(disableTicket)
+	objectives := slo.objectives(sloClass)
+	sampleConfig := slo.samples(sloClass)
 
 	var alertRules []rulefmt.RuleNode
 
 	if slo.ErrorRateRecord.AlertMethod != "" {
 		errorMethod := methods.Get(slo.ErrorRateRecord.AlertMethod)
 		if errorMethod == nil {
-			log.Panicf("alertMethod %s is not valid", slo.ErrorRateRecord.AlertMethod)
+			return nil, slo.generateError(ErrorBlock, "alertMethod", fmt.Errorf("alertMethod %s is not valid", slo.ErrorRateRecord.AlertMethod))
 		}
 
 		errorRules, err := errorMethod.AlertForError(&methods.AlertErrorOptions{
 			ServiceName:        slo.Name,
-			AvailabilityTarget: objectives.Availability,
-			SLOWindow:          time.Duration(objectives.Window),
-			ShortWindow:        slo.ErrorRateRecord.GetShortWindow(),
-			Windows:            slo.ErrorRateRecord.Windows,
-			AlertWindow:        slo.ErrorRateRecord.AlertWindow,
+			AvailabilityTarget: float64(objectives.Availability),
+			RateOptions:        slo.ErrorRateRecord.rateOptions(&objectives),
 			AlertWait:          slo.ErrorRateRecord.AlertWait,
-			BurnRate:           slo.ErrorRateRecord.BurnRate,
+			Annotate:           slo.AlertAnnotations != nil,
+			BudgetWindow:       time.Duration(objectives.budgetWindow()),
+			ObjectiveMetrics:   slo.ObjectiveMetrics,
+			Samples:            sampleConfig,
 		})
 		if err != nil {
-			log.Panicf("Could not generate alert, err: %s", err.Error())
+			return nil, slo.generateError(ErrorBlock, methodField(err), err)
+		}
+		if err := checkAlertExprs(errorRules); err != nil {
+			return nil, slo.generateError(ErrorBlock, "alert", err)
 		}
 		alertRules = append(alertRules, ruleNodes(errorRules)...)
 	}
@@ -133,29 +229,24 @@ func (slo *SLO) GenerateAlertRules(sloClass *Class, disableTicket bool) []rulefm
 	if slo.LatencyRecord.AlertMethod != "" {
 		latencyMethod := methods.Get(slo.LatencyRecord.AlertMethod)
 		if latencyMethod == nil {
-			log.Panicf("alertMethod %s is not valid", slo.LatencyRecord.AlertMethod)
+			return nil, slo.generateError(LatencyBlock, "alertMethod", fmt.Errorf("alertMethod %s is not valid", slo.LatencyRecord.AlertMethod))
 		}
 
 		if objectives.Latency != nil {
-			latencyRules, err := latencyMethod.AlertForLatency(&methods.AlertLatencyOptions{
-				ServiceName: slo.Name,
-				Targets:     objectives.Latency,
-				SLOWindow:   time.Duration(objectives.Window),
-				ShortWindow: slo.LatencyRecord.GetShortWindow(),
-				Windows:     slo.LatencyRecord.Windows,
-				AlertWindow: slo.LatencyRecord.AlertWindow,
-				AlertWait:   slo.LatencyRecord.AlertWait,
-				BurnRate:    slo.ErrorRateRecord.BurnRate,
-			})
+			latencyRules, err := slo.latencyAlertRules(latencyMethod, &objectives, sampleConfig)
 			if err != nil {
-				log.Panicf("Could not generate alert, err: %s", err.Error())
+				return nil, err
 			}
-			alertRules = append(alertRules, ruleNodes(latencyRules)...)
+			alertRules = append(alertRules, latencyRules...)
 		}
 	}
 
-	for _, rule := range alertRules {
-		slo.fillMetadata(&rule)
+	for i := range alertRules {
+		data := slo.templateData(&alertRules[i], sloClass, &objectives)
+		slo.fillMetadata(&alertRules[i])
+		if err := slo.renderAnnotations(&alertRules[i], data); err != nil {
+			return nil, err
+		}
 	}
 
 	if disableTicket {
@@ -167,10 +258,10 @@ func (slo *SLO) GenerateAlertRules(sloClass *Class, disableTicket bool) []rulefm
 			}
 		}
 
-		return alertRulesWithoutTicket
+		return alertRulesWithoutTicket, nil
 	}
 
-	return alertRules
+	return alertRules, nil
 }
 
 func (slo *SLO) fillMetadata(rule *rulefmt.RuleNode) {
@@ -178,28 +269,41 @@ func (slo *SLO) fillMetadata(rule *rulefmt.RuleNode) {
 		rule.Labels[label] = value
 	}
 
+	if slo.AlertAnnotations != nil {
+		for name, value := range slo.AlertAnnotations.links() {
+			rule.Annotations[name] = value
+		}
+	}
+
 	for label, value := range slo.Annotations {
 		rule.Annotations[label] = value
 	}
 }
 
-func (slo *SLO) GenerateGroupRules(sloClass *Class, disableTicket bool) []rulefmt.RuleGroup {
+func (slo *SLO) GenerateGroupRules(sloClass *Class, disableTicket bool) ([]rulefmt.RuleGroup, error) {
 	var rules []rulefmt.RuleGroup
 
-	objectives := slo.Objectives
-	if sloClass != nil {
-		objectives = sloClass.Objectives
+	slo, err := slo.renderLabels(sloClass)
+	if err != nil {
+		return nil, err
 	}
+	disableTicket = sloClass.ticketsDisabled(disableTicket)
+	objectives := slo.objectives(sloClass)
 	latencyBuckets := objectives.LatencyBuckets()
 	if len(slo.LatencyRecord.Buckets) > 0 {
 		latencyBuckets = slo.LatencyRecord.Buckets
 	}
+	quantiles := slo.quantiles(sloClass)
+	groups, err := slo.sampleGroups(sloClass, disableTicket)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, sample := range samples.DefaultSamples {
+	for _, sample := range groups {
 
 		interval, err := model.ParseDuration(sample.Interval)
 		if err != nil {
-			log.Fatal(err)
+			return nil, slo.generateError("", "interval of sample "+sample.Name, err)
 		}
 		ruleGroup := rulefmt.RuleGroup{
 			Name:     fmt.Sprintf("slo:%s:%s", slo.Name, sample.Name),
@@ -208,11 +312,12 @@ func (slo *SLO) GenerateGroupRules(sloClass *Class, disableTicket bool) []rulefm
 		}
 
 		for _, bucket := range sample.Buckets {
-			if disableTicket && samples.IsTicketSample(bucket) {
-				continue
+			bucketRules, err := slo.generateRules(bucket, latencyBuckets, quantiles)
+			if err != nil {
+				return nil, err
 			}
 
-			ruleGroup.Rules = append(ruleGroup.Rules, slo.generateRules(bucket, latencyBuckets)...)
+			ruleGroup.Rules = append(ruleGroup.Rules, bucketRules...)
 		}
 
 		if len(ruleGroup.Rules) > 0 {
@@ -220,7 +325,7 @@ func (slo *SLO) GenerateGroupRules(sloClass *Class, disableTicket bool) []rulefm
 		}
 	}
 
-	return rules
+	return rules, nil
 }
 
 func (slo *SLO) labels() map[string]string {
@@ -234,26 +339,42 @@ func (slo *SLO) labels() map[string]string {
 	return labels
 }
 
-func (slo *SLO) generateRules(bucket string, latencyBuckets []string) []rulefmt.RuleNode {
+func (slo *SLO) generateRules(bucket string, latencyBuckets []string, quantiles []float64) ([]rulefmt.RuleNode, error) {
 	var rules []rulefmt.RuleNode
 	if slo.TrafficRateRecord.Expr != "" {
 		trafficRateRecord := rulefmt.RuleNode{
 			Labels: slo.labels(),
 		}
 
+		expr := slo.TrafficRateRecord.ComputeExpr(bucket, "")
+		if err := checkExpr(expr, bucket, "$window="+bucket); err != nil {
+			return nil, slo.generateError(TrafficBlock, "expr", err)
+		}
+
 		trafficRateRecord.Record.SetString(fmt.Sprintf("slo:service_traffic:ratio_rate_%s", bucket))
-		trafficRateRecord.Expr.SetString(slo.TrafficRateRecord.ComputeExpr(bucket, ""))
+		trafficRateRecord.Expr.SetString(expr)
 
 		rules = append(rules, trafficRateRecord)
 	}
 
-	if slo.ErrorRateRecord.Expr != "" {
+	if slo.ErrorRateRecord.IsEventBased() {
+		eventRules, err := slo.generateEventRules(bucket)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, eventRules...)
+	} else if slo.ErrorRateRecord.Expr != "" {
 		errorRateRecord := rulefmt.RuleNode{
 			Labels: slo.labels(),
 		}
 
+		expr := slo.ErrorRateRecord.ComputeExpr(bucket, "")
+		if err := checkExpr(expr, bucket, "$window="+bucket); err != nil {
+			return nil, slo.generateError(ErrorBlock, "expr", err)
+		}
+
 		errorRateRecord.Record.SetString(fmt.Sprintf("slo:service_errors_total:ratio_rate_%s", bucket))
-		errorRateRecord.Expr.SetString(slo.ErrorRateRecord.ComputeExpr(bucket, ""))
+		errorRateRecord.Expr.SetString(expr)
 		rules = append(rules, errorRateRecord)
 	}
 
@@ -263,26 +384,61 @@ func (slo *SLO) generateRules(bucket string, latencyBuckets []string) []rulefmt.
 				Labels: slo.labels(),
 			}
 
-			latencyQuantileRecord.Record.SetString(fmt.Sprintf("slo:service_latency:%s_%s", quantile.name, bucket))
-			latencyQuantileRecord.Expr.SetString(slo.LatencyQuantileRecord.ComputeQuantile(bucket, quantile.quantile))
+			expr := slo.LatencyQuantileRecord.ComputeQuantile(bucket, quantile)
+			if err := checkExpr(expr, bucket, fmt.Sprintf("$window=%s, $quantile=%g", bucket, quantile)); err != nil {
+				return nil, slo.generateError(LatencyQuantileBlock, "expr", err)
+			}
+
+			latencyQuantileRecord.Record.SetString(fmt.Sprintf("slo:service_latency:%s_%s", QuantileName(quantile), bucket))
+			latencyQuantileRecord.Expr.SetString(expr)
 			rules = append(rules, latencyQuantileRecord)
 		}
 	}
 
-	if slo.LatencyRecord.Expr != "" {
+	if slo.hasLatencySLI() {
 		for _, latencyBucket := range latencyBuckets {
 			latencyRateRecord := rulefmt.RuleNode{
 				Labels: slo.labels(),
 			}
+
+			expr := slo.ComputeLatencyExpr(bucket, latencyBucket)
+			if err := checkExpr(slo.checkedLatencyExpr(bucket, latencyBucket), bucket, fmt.Sprintf("$window=%s, $le=%s", bucket, latencyBucket)); err != nil {
+				return nil, slo.generateError(LatencyBlock, "expr", err)
+			}
+
 			latencyRateRecord.Record.SetString("slo:service_latency:ratio_rate_" + bucket)
-			latencyRateRecord.Expr.SetString(slo.LatencyRecord.ComputeExpr(bucket, latencyBucket))
+			latencyRateRecord.Expr.SetString(expr)
 			latencyRateRecord.Labels["le"] = latencyBucket
 
 			rules = append(rules, latencyRateRecord)
 		}
 	}
 
-	return rules
+	return rules, nil
+}
+
+// generateEventRules records the rates of errors and requests separately, so longer windows can be
+// aggregated weighting each sample, then records the ratio of errors from them
+func (slo *SLO) generateEventRules(bucket string) ([]rulefmt.RuleNode, error) {
+	errorsExpr, requestsExpr := slo.ErrorRateRecord.ComputeEvents(bucket)
+	eventsField := "badEvents"
+	if slo.ErrorRateRecord.BadEvents == "" {
+		eventsField = "goodEvents"
+	}
+	if err := checkExpr(errorsExpr, bucket, "$window="+bucket); err != nil {
+		return nil, slo.generateError(ErrorBlock, eventsField, err)
+	}
+	if err := checkExpr(requestsExpr, bucket, "$window="+bucket); err != nil {
+		return nil, slo.generateError(ErrorBlock, "totalEvents", err)
+	}
+
+	ratio := fmt.Sprintf("slo:service_errors:rate_%s%s / slo:service_requests:rate_%s%s", bucket, slo.selector(), bucket, slo.selector())
+
+	return []rulefmt.RuleNode{
+		slo.recordRule("slo:service_errors:rate_"+bucket, errorsExpr, nil),
+		slo.recordRule("slo:service_requests:rate_"+bucket, requestsExpr, nil),
+		slo.recordRule("slo:service_errors_total:ratio_rate_"+bucket, ratio, nil),
+	}, nil
 }
 
 func ruleNodes(origin []rulefmt.Rule) []rulefmt.RuleNode {
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/globocom/slo-generator/methods"
+	"github.com/globocom/slo-generator/samples"
 )
 
 func TestSLOGenerateGroupRules(t *testing.T) {
@@ -48,7 +49,8 @@ func TestSLOGenerateGroupRules(t *testing.T) {
 		},
 	}
 
-	groupRules := slo.GenerateGroupRules(nil, false)
+	groupRules, err := slo.GenerateGroupRules(nil, false)
+	assert.NoError(t, err)
 	assert.Len(t, groupRules, 3)
 
 	assert.Equal(t, rulefmt.RuleGroup{
@@ -331,7 +333,8 @@ func TestSLOGenerateGroupRulesWithLatencyQuantile(t *testing.T) {
 		},
 	}
 
-	groupRules := slo.GenerateGroupRules(nil, false)
+	groupRules, err := slo.GenerateGroupRules(nil, false)
+	assert.NoError(t, err)
 	assert.Len(t, groupRules, 3)
 
 	assert.Equal(t, rulefmt.RuleGroup{
@@ -469,6 +472,62 @@ func TestSLOGenerateGroupRulesWithLatencyQuantile(t *testing.T) {
 	}, groupRules[2])
 }
 
+func TestSLOGenerateGroupRulesWithCustomQuantiles(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		LatencyQuantileRecord: ExprBlock{
+			Expr:      "histogram_quantile($quantile, sum by (le) (rate(http_bucket[$window])))",
+			Quantiles: []float64{0.9, 0.999},
+		},
+		Samples: &samples.Config{
+			Groups: []samples.Sample{
+				{Name: "short", Interval: "30s", Buckets: []string{"5m"}},
+			},
+		},
+	}
+
+	groupRules, err := slo.GenerateGroupRules(nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, ruleNodes([]rulefmt.Rule{
+		{
+			Record: "slo:service_latency:p90_5m",
+			Expr:   "histogram_quantile(0.9, sum by (le) (rate(http_bucket[5m])))",
+			Labels: map[string]string{"service": "my-service"},
+		},
+		{
+			Record: "slo:service_latency:p999_5m",
+			Expr:   "histogram_quantile(0.999, sum by (le) (rate(http_bucket[5m])))",
+			Labels: map[string]string{"service": "my-service"},
+		},
+	}), groupRules[0].Rules)
+
+	class := &Class{
+		Name:                  "HIGH_CARDINALITY",
+		LatencyQuantileRecord: &ClassQuantileRecord{Quantiles: []float64{}},
+	}
+	// quantiles of the SLO are kept over the ones of its class
+	classRules, err := slo.GenerateGroupRules(class, false)
+	assert.NoError(t, err)
+	assert.Equal(t, groupRules, classRules)
+
+	slo.LatencyQuantileRecord.Quantiles = nil
+	groupRules, err = slo.GenerateGroupRules(class, false)
+	assert.NoError(t, err)
+	assert.Len(t, groupRules, 0)
+}
+
+func TestQuantileName(t *testing.T) {
+	assert.Equal(t, "p50", QuantileName(0.5))
+	assert.Equal(t, "p90", QuantileName(0.9))
+	assert.Equal(t, "p99", QuantileName(0.99))
+	assert.Equal(t, "p999", QuantileName(0.999))
+	assert.Equal(t, "p9999", QuantileName(0.9999))
+	assert.Equal(t, "p995", QuantileName(0.995))
+	assert.Equal(t, "p05", QuantileName(0.05))
+	assert.Equal(t, "p099", QuantileName(0.099))
+	assert.Equal(t, "p005", QuantileName(0.005))
+}
+
 func TestSLOGenerateGroupRulesWithAutoDiscovery(t *testing.T) {
 	slo := &SLO{
 		Name:        "auto-discover-services",
@@ -485,11 +544,12 @@ func TestSLOGenerateGroupRulesWithAutoDiscovery(t *testing.T) {
 				"0.1",
 				"1.0",
 			},
-			Expr: "sum(bucket{le=\"$le\"}[$window])/sum(bucket{le=\"+Inf\"}[$window])",
+			Expr: "sum(rate(bucket{le=\"$le\"}[$window]))/sum(rate(bucket{le=\"+Inf\"}[$window]))",
 		},
 	}
 
-	groupRules := slo.GenerateGroupRules(nil, false)
+	groupRules, err := slo.GenerateGroupRules(nil, false)
+	assert.NoError(t, err)
 	assert.Len(t, groupRules, 3)
 
 	assert.Equal(t, rulefmt.RuleGroup{
@@ -509,12 +569,12 @@ func TestSLOGenerateGroupRulesWithAutoDiscovery(t *testing.T) {
 			},
 			{
 				Record: "slo:service_latency:ratio_rate_5m",
-				Expr:   "sum(bucket{le=\"0.1\"}[5m])/sum(bucket{le=\"+Inf\"}[5m])",
+				Expr:   "sum(rate(bucket{le=\"0.1\"}[5m]))/sum(rate(bucket{le=\"+Inf\"}[5m]))",
 				Labels: map[string]string{"le": "0.1"},
 			},
 			{
 				Record: "slo:service_latency:ratio_rate_5m",
-				Expr:   "sum(bucket{le=\"1.0\"}[5m])/sum(bucket{le=\"+Inf\"}[5m])",
+				Expr:   "sum(rate(bucket{le=\"1.0\"}[5m]))/sum(rate(bucket{le=\"+Inf\"}[5m]))",
 				Labels: map[string]string{"le": "1.0"},
 			},
 			// 30m
@@ -530,12 +590,12 @@ func TestSLOGenerateGroupRulesWithAutoDiscovery(t *testing.T) {
 			},
 			{
 				Record: "slo:service_latency:ratio_rate_30m",
-				Expr:   "sum(bucket{le=\"0.1\"}[30m])/sum(bucket{le=\"+Inf\"}[30m])",
+				Expr:   "sum(rate(bucket{le=\"0.1\"}[30m]))/sum(rate(bucket{le=\"+Inf\"}[30m]))",
 				Labels: map[string]string{"le": "0.1"},
 			},
 			{
 				Record: "slo:service_latency:ratio_rate_30m",
-				Expr:   "sum(bucket{le=\"1.0\"}[30m])/sum(bucket{le=\"+Inf\"}[30m])",
+				Expr:   "sum(rate(bucket{le=\"1.0\"}[30m]))/sum(rate(bucket{le=\"+Inf\"}[30m]))",
 				Labels: map[string]string{"le": "1.0"},
 			},
 			// 1h
@@ -551,12 +611,12 @@ func TestSLOGenerateGroupRulesWithAutoDiscovery(t *testing.T) {
 			},
 			{
 				Record: "slo:service_latency:ratio_rate_1h",
-				Expr:   "sum(bucket{le=\"0.1\"}[1h])/sum(bucket{le=\"+Inf\"}[1h])",
+				Expr:   "sum(rate(bucket{le=\"0.1\"}[1h]))/sum(rate(bucket{le=\"+Inf\"}[1h]))",
 				Labels: map[string]string{"le": "0.1"},
 			},
 			{
 				Record: "slo:service_latency:ratio_rate_1h",
-				Expr:   "sum(bucket{le=\"1.0\"}[1h])/sum(bucket{le=\"+Inf\"}[1h])",
+				Expr:   "sum(rate(bucket{le=\"1.0\"}[1h]))/sum(rate(bucket{le=\"+Inf\"}[1h]))",
 				Labels: map[string]string{"le": "1.0"},
 			},
 		}),
@@ -579,12 +639,12 @@ func TestSLOGenerateGroupRulesWithAutoDiscovery(t *testing.T) {
 			},
 			{
 				Record: "slo:service_latency:ratio_rate_2h",
-				Expr:   "sum(bucket{le=\"0.1\"}[2h])/sum(bucket{le=\"+Inf\"}[2h])",
+				Expr:   "sum(rate(bucket{le=\"0.1\"}[2h]))/sum(rate(bucket{le=\"+Inf\"}[2h]))",
 				Labels: map[string]string{"le": "0.1"},
 			},
 			{
 				Record: "slo:service_latency:ratio_rate_2h",
-				Expr:   "sum(bucket{le=\"1.0\"}[2h])/sum(bucket{le=\"+Inf\"}[2h])",
+				Expr:   "sum(rate(bucket{le=\"1.0\"}[2h]))/sum(rate(bucket{le=\"+Inf\"}[2h]))",
 				Labels: map[string]string{"le": "1.0"},
 			},
 
@@ -601,12 +661,12 @@ func TestSLOGenerateGroupRulesWithAutoDiscovery(t *testing.T) {
 			},
 			{
 				Record: "slo:service_latency:ratio_rate_6h",
-				Expr:   "sum(bucket{le=\"0.1\"}[6h])/sum(bucket{le=\"+Inf\"}[6h])",
+				Expr:   "sum(rate(bucket{le=\"0.1\"}[6h]))/sum(rate(bucket{le=\"+Inf\"}[6h]))",
 				Labels: map[string]string{"le": "0.1"},
 			},
 			{
 				Record: "slo:service_latency:ratio_rate_6h",
-				Expr:   "sum(bucket{le=\"1.0\"}[6h])/sum(bucket{le=\"+Inf\"}[6h])",
+				Expr:   "sum(rate(bucket{le=\"1.0\"}[6h]))/sum(rate(bucket{le=\"+Inf\"}[6h]))",
 				Labels: map[string]string{"le": "1.0"},
 			},
 		}),
@@ -630,12 +690,12 @@ func TestSLOGenerateGroupRulesWithAutoDiscovery(t *testing.T) {
 
 			{
 				Record: "slo:service_latency:ratio_rate_1d",
-				Expr:   "sum(bucket{le=\"0.1\"}[1d])/sum(bucket{le=\"+Inf\"}[1d])",
+				Expr:   "sum(rate(bucket{le=\"0.1\"}[1d]))/sum(rate(bucket{le=\"+Inf\"}[1d]))",
 				Labels: map[string]string{"le": "0.1"},
 			},
 			{
 				Record: "slo:service_latency:ratio_rate_1d",
-				Expr:   "sum(bucket{le=\"1.0\"}[1d])/sum(bucket{le=\"+Inf\"}[1d])",
+				Expr:   "sum(rate(bucket{le=\"1.0\"}[1d]))/sum(rate(bucket{le=\"+Inf\"}[1d]))",
 				Labels: map[string]string{"le": "1.0"},
 			},
 
@@ -652,12 +712,12 @@ func TestSLOGenerateGroupRulesWithAutoDiscovery(t *testing.T) {
 			},
 			{
 				Record: "slo:service_latency:ratio_rate_3d",
-				Expr:   "sum(bucket{le=\"0.1\"}[3d])/sum(bucket{le=\"+Inf\"}[3d])",
+				Expr:   "sum(rate(bucket{le=\"0.1\"}[3d]))/sum(rate(bucket{le=\"+Inf\"}[3d]))",
 				Labels: map[string]string{"le": "0.1"},
 			},
 			{
 				Record: "slo:service_latency:ratio_rate_3d",
-				Expr:   "sum(bucket{le=\"1.0\"}[3d])/sum(bucket{le=\"+Inf\"}[3d])",
+				Expr:   "sum(rate(bucket{le=\"1.0\"}[3d]))/sum(rate(bucket{le=\"+Inf\"}[3d]))",
 				Labels: map[string]string{"le": "1.0"},
 			},
 		}),
@@ -698,7 +758,8 @@ func TestSLOGenerateAlertRules(t *testing.T) {
 		},
 	}
 
-	alertRules := slo.GenerateAlertRules(nil, false)
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
 	assert.Len(t, alertRules, 4)
 
 	assert.Equal(t, ruleNode(rulefmt.Rule{
@@ -788,9 +849,9 @@ func TestSLOGenerateAlertRulesWithInvalidAlert(t *testing.T) {
 		},
 	}
 
-	assert.PanicsWithValue(t, "alertMethod INVALID is not valid", func() {
-		slo.GenerateAlertRules(nil, false)
-	})
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.Nil(t, alertRules)
+	assert.EqualError(t, err, "could not generate SLO \"\", error record, field alertMethod: alertMethod INVALID is not valid")
 }
 
 func TestSLOGenerateAlertRulesWithInvalidLatencyMethod(t *testing.T) {
@@ -805,9 +866,9 @@ func TestSLOGenerateAlertRulesWithInvalidLatencyMethod(t *testing.T) {
 		},
 	}
 
-	assert.PanicsWithValue(t, "alertMethod INVALID is not valid", func() {
-		slo.GenerateAlertRules(nil, false)
-	})
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.Nil(t, alertRules)
+	assert.EqualError(t, err, "could not generate SLO \"\", latency record, field alertMethod: alertMethod INVALID is not valid")
 }
 
 func TestSLOGenerateAlertRulesWithCustomWindows(t *testing.T) {
@@ -884,7 +945,8 @@ func TestSLOGenerateAlertRulesWithCustomWindows(t *testing.T) {
 		},
 	}
 
-	alertRules := slo.GenerateAlertRules(nil, false)
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
 	assert.Len(t, alertRules, 4)
 
 	assert.Equal(t, ruleNode(rulefmt.Rule{
@@ -1024,7 +1086,8 @@ func TestSLOGenerateAlertRulesWithSingleBurnRate(t *testing.T) {
 		},
 	}
 
-	alertRules := slo.GenerateAlertRules(nil, false)
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
 	assert.Len(t, alertRules, 2)
 
 	assert.Equal(t, ruleNode(rulefmt.Rule{
@@ -1085,7 +1148,8 @@ func TestSLOGenerateAlertRulesWithoutExpressions(t *testing.T) {
 		},
 	}
 
-	alertRules := slo.GenerateAlertRules(nil, false)
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
 	assert.Len(t, alertRules, 4)
 
 	assert.Equal(t, ruleNode(rulefmt.Rule{
@@ -1209,7 +1273,8 @@ func TestSLOGenerateAlertRulesWithSLOCLass(t *testing.T) {
 		},
 	}
 
-	alertRules := slo.GenerateAlertRules(sloClass, false)
+	alertRules, err := slo.GenerateAlertRules(sloClass, false)
+	assert.NoError(t, err)
 	assert.Len(t, alertRules, 4)
 
 	assert.Equal(t, ruleNode(rulefmt.Rule{
@@ -1290,7 +1355,8 @@ func TestSLOGenerateAlertRulesWithSLOCLass(t *testing.T) {
 		Annotations: slo.Annotations,
 	}), alertRules[3])
 
-	alertRules = slo.GenerateAlertRules(noLatencyClass, false)
+	alertRules, err = slo.GenerateAlertRules(noLatencyClass, false)
+	assert.NoError(t, err)
 	assert.Len(t, alertRules, 2)
 }
 
@@ -1326,7 +1392,8 @@ func TestSLOGenerateAlertRulesWithoutTickets(t *testing.T) {
 		},
 	}
 
-	alertRules := slo.GenerateAlertRules(nil, true)
+	alertRules, err := slo.GenerateAlertRules(nil, true)
+	assert.NoError(t, err)
 	assert.Len(t, alertRules, 2)
 
 	assert.Equal(t, ruleNode(rulefmt.Rule{
@@ -1406,7 +1473,8 @@ func TestSLOGenerateGroupRulesWithoutTickets(t *testing.T) {
 		},
 	}
 
-	groupRules := slo.GenerateGroupRules(nil, true)
+	groupRules, err := slo.GenerateGroupRules(nil, true)
+	assert.NoError(t, err)
 	assert.Len(t, groupRules, 2)
 
 	assert.Equal(t, groupRules[0], rulefmt.RuleGroup{
@@ -1565,3 +1633,511 @@ func TestSLOGenerateGroupRulesWithoutTickets(t *testing.T) {
 		}),
 	})
 }
+
+func TestSLOGenerateGroupRulesWithCustomSamples(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+samples:
+  groups:
+    - name: daily
+      interval: 5m
+      buckets: [1h, 1d]
+    - name: weekly
+      interval: 30m
+      buckets: [4d, 7d]
+  ticketBuckets: [4d, 7d]
+classes:
+  - name: MONTHLY
+    objectives:
+      availability: 99.9
+    samples:
+      groups:
+        - name: monthly
+          interval: 1h
+          buckets: [28d]
+slos:
+  - name: my-service
+    objectives:
+      availability: 99.9
+    errorRateRecord:
+      alertMethod: simple
+      alertWindow: 7d
+      expr: sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	slo := spec.SLOS[0]
+	groupRules, err := slo.GenerateGroupRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, groupRules, 2)
+	assert.Equal(t, "slo:my-service:daily", groupRules[0].Name)
+	assert.Equal(t, model.Duration(5*time.Minute), groupRules[0].Interval)
+	assert.Equal(t, "slo:my-service:weekly", groupRules[1].Name)
+	assert.Equal(t, model.Duration(30*time.Minute), groupRules[1].Interval)
+	assert.Equal(t, "slo:service_errors_total:ratio_rate_7d", groupRules[1].Rules[1].Record.Value)
+	assert.Equal(t, "sum(rate(http_errors[7d]))/sum(rate(http_total[7d]))", groupRules[1].Rules[1].Expr.Value)
+
+	// 7d is a ticket bucket, but the page alert still needs it
+	groupRules, err = slo.GenerateGroupRules(nil, true)
+	assert.NoError(t, err)
+	assert.Len(t, groupRules, 2)
+	assert.Equal(t, "slo:my-service:weekly", groupRules[1].Name)
+	assert.Len(t, groupRules[1].Rules, 1)
+	assert.Equal(t, "slo:service_errors_total:ratio_rate_7d", groupRules[1].Rules[0].Record.Value)
+
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "slo:service_errors_total:ratio_rate_7d{service=\"my-service\"} > 1 * 0.001", alertRules[0].Expr.Value)
+
+	slo.ErrorRateRecord.AlertWindow = "28d"
+	groupRules, err = slo.GenerateGroupRules(&spec.Classes[0], false)
+	assert.NoError(t, err)
+	assert.Len(t, groupRules, 1)
+	assert.Equal(t, "slo:my-service:monthly", groupRules[0].Name)
+	assert.Equal(t, "slo:service_errors_total:ratio_rate_28d", groupRules[0].Rules[0].Record.Value)
+
+	slo.ErrorRateRecord.AlertWindow = "7d"
+	alertRules, err = slo.GenerateAlertRules(&spec.Classes[0], false)
+	assert.Nil(t, alertRules)
+	assert.EqualError(t, err, "could not generate SLO \"my-service\", error record, field alertWindow: Sample 7d is not a valid sample, valid samples: 28d")
+
+	// recording rules do not depend on the alerts to be valid
+	groupRules, err = slo.GenerateGroupRules(&spec.Classes[0], false)
+	assert.NoError(t, err)
+	assert.Len(t, groupRules, 1)
+	assert.Equal(t, "slo:service_errors_total:ratio_rate_7d", groupRules[0].Rules[0].Record.Value)
+	assert.Equal(t, "slo:service_errors_total:ratio_rate_28d", groupRules[0].Rules[1].Record.Value)
+}
+
+func TestSLOGenerateGroupRulesWithDerivedWindows(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Availability: 99.9,
+			Window:       model.Duration(30 * 24 * time.Hour),
+		},
+		ErrorRateRecord: ExprBlock{
+			AlertMethod: "multi-window",
+			Expr:        "sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))",
+			Windows: []methods.Window{
+				{Duration: model.Duration(4 * time.Hour), Consumption: 5, Notification: "page"},
+			},
+		},
+		Samples: &samples.Config{
+			Groups: []samples.Sample{
+				{Name: "short", Interval: "30s", Buckets: []string{"5m", "1h"}},
+				{Name: "long", Interval: "5m", Buckets: []string{"1d"}},
+			},
+		},
+	}
+
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, alertRules, 1)
+	assert.Contains(t, alertRules[0].Expr.Value, "slo:service_errors_total:ratio_rate_4h")
+	assert.Contains(t, alertRules[0].Expr.Value, "slo:service_errors_total:ratio_rate_20m")
+
+	groupRules, err := slo.GenerateGroupRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, groupRules, 2)
+
+	records := func(group rulefmt.RuleGroup) []string {
+		var result []string
+		for _, rule := range group.Rules {
+			result = append(result, rule.Record.Value)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{
+		"slo:service_errors_total:ratio_rate_5m",
+		"slo:service_errors_total:ratio_rate_20m",
+		"slo:service_errors_total:ratio_rate_1h",
+	}, records(groupRules[0]))
+	assert.Equal(t, []string{
+		"slo:service_errors_total:ratio_rate_4h",
+		"slo:service_errors_total:ratio_rate_1d",
+	}, records(groupRules[1]))
+}
+
+func TestSLOGenerateGroupRulesWithWindowsLongerThanSamples(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Availability: 99.9,
+		},
+		ErrorRateRecord: ExprBlock{
+			AlertMethod: "multi-window",
+			Expr:        "sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))",
+		},
+		Samples: &samples.Config{
+			Groups: []samples.Sample{
+				{Name: "short", Interval: "30s", Buckets: []string{"5m", "1h"}},
+			},
+		},
+	}
+
+	// windows longer than every bucket are not evaluated every 30s, but in a group of their own
+	groupRules, err := slo.GenerateGroupRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, groupRules, 2)
+	assert.Equal(t, "slo:my-service:short", groupRules[0].Name)
+	assert.Equal(t, []string{
+		"slo:service_errors_total:ratio_rate_5m",
+		"slo:service_errors_total:ratio_rate_30m",
+		"slo:service_errors_total:ratio_rate_1h",
+	}, recordNames(groupRules[0]))
+	assert.Equal(t, "slo:my-service:derived", groupRules[1].Name)
+	assert.Equal(t, model.Duration(5*time.Minute), groupRules[1].Interval)
+	assert.Equal(t, []string{
+		"slo:service_errors_total:ratio_rate_2h",
+		"slo:service_errors_total:ratio_rate_6h",
+		"slo:service_errors_total:ratio_rate_1d",
+		"slo:service_errors_total:ratio_rate_3d",
+	}, recordNames(groupRules[1]))
+
+	slo.Samples.Groups = append(slo.Samples.Groups, samples.Sample{Name: "hourly", Interval: "10m", Buckets: []string{"2h"}})
+	groupRules, err = slo.GenerateGroupRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, groupRules, 3)
+	assert.Equal(t, []string{"slo:service_errors_total:ratio_rate_2h"}, recordNames(groupRules[1]))
+	assert.Equal(t, model.Duration(10*time.Minute), groupRules[2].Interval)
+}
+
+func recordNames(group rulefmt.RuleGroup) []string {
+	var names []string
+	for _, rule := range group.Rules {
+		names = append(names, rule.Record.Value)
+	}
+
+	return names
+}
+
+func TestSLOGenerateGroupRulesWithEvents(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Availability: 99.9,
+		},
+		ErrorRateRecord: ExprBlock{
+			AlertMethod: "multi-window",
+			GoodEvents:  "http_total{status!~\"5..\"}",
+			TotalEvents: "http_total",
+		},
+		Samples: &samples.Config{
+			Groups: []samples.Sample{
+				{Name: "short", Interval: "30s", Buckets: []string{"5m"}},
+			},
+		},
+	}
+
+	// windows of alerts longer than 5m are recorded in a group of their own
+	groupRules, err := slo.GenerateGroupRules(nil, true)
+	assert.NoError(t, err)
+	assert.Len(t, groupRules, 2)
+	assert.Equal(t, []rulefmt.RuleNode{
+		ruleNode(rulefmt.Rule{
+			Record: "slo:service_errors:rate_5m",
+			Expr:   "sum(rate(http_total[5m])) - sum(rate(http_total{status!~\"5..\"}[5m]))",
+			Labels: map[string]string{"service": "my-service"},
+		}),
+		ruleNode(rulefmt.Rule{
+			Record: "slo:service_requests:rate_5m",
+			Expr:   "sum(rate(http_total[5m]))",
+			Labels: map[string]string{"service": "my-service"},
+		}),
+		ruleNode(rulefmt.Rule{
+			Record: "slo:service_errors_total:ratio_rate_5m",
+			Expr:   "slo:service_errors:rate_5m{service=\"my-service\"} / slo:service_requests:rate_5m{service=\"my-service\"}",
+			Labels: map[string]string{"service": "my-service"},
+		}),
+	}, groupRules[0].Rules[:3])
+
+	slo.ErrorRateRecord.GoodEvents = ""
+	slo.ErrorRateRecord.BadEvents = "http_total{status=~\"5..\"}"
+	groupRules, err = slo.GenerateGroupRules(nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "sum(rate(http_total{status=~\"5..\"}[5m]))", groupRules[0].Rules[0].Expr.Value)
+
+	slo.ErrorRateRecord.BadEvents = "http_total{"
+	groupRules, err = slo.GenerateGroupRules(nil, true)
+	assert.Nil(t, groupRules)
+	assert.Contains(t, err.Error(), "could not generate SLO \"my-service\", error record, field badEvents: invalid PromQL for window")
+}
+
+func TestSLOGenerateAlertRulesWithIndependentBurnRates(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Availability: 99.9,
+			Latency: []methods.LatencyTarget{
+				{LE: "0.1", Target: 95},
+			},
+		},
+		ErrorRateRecord: ExprBlock{
+			AlertMethod: "simple",
+			AlertWindow: "1h",
+			BurnRate:    2,
+		},
+		LatencyRecord: ExprBlock{
+			AlertMethod: "simple",
+			AlertWindow: "1h",
+			BurnRate:    5,
+		},
+	}
+
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, alertRules, 2)
+	assert.Equal(t, "slo:service_errors_total:ratio_rate_1h{service=\"my-service\"} > 2 * 0.001", alertRules[0].Expr.Value)
+	assert.Equal(t, "slo:service_latency:ratio_rate_1h{le=\"0.1\", service=\"my-service\"} < 1 - 5 * 0.05", alertRules[1].Expr.Value)
+
+	slo.LatencyRecord.BurnRate = 0
+	alertRules, err = slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "slo:service_latency:ratio_rate_1h{le=\"0.1\", service=\"my-service\"} < 1 - 1 * 0.05", alertRules[1].Expr.Value)
+}
+
+func TestSLOGenerateAlertRulesWithSeverity(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Availability: 99.9,
+			Latency: []methods.LatencyTarget{
+				{LE: "0.1", Target: 95},
+			},
+		},
+		ErrorRateRecord: ExprBlock{
+			AlertMethod: "simple",
+			AlertWindow: "1h",
+			Severity:    "ticket",
+		},
+		LatencyRecord: ExprBlock{
+			AlertMethod: "multi-window",
+			Severity:    "ticket",
+		},
+	}
+
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, alertRules, 2)
+	assert.Equal(t, "slo:my-service.errors.ticket", alertRules[0].Alert.Value)
+	assert.Equal(t, "ticket", alertRules[0].Labels["severity"])
+	assert.Equal(t, "slo:my-service.latency.ticket", alertRules[1].Alert.Value)
+	assert.Equal(t, "ticket", alertRules[1].Labels["severity"])
+
+	alertRules, err = slo.GenerateAlertRules(nil, true)
+	assert.NoError(t, err)
+	assert.Len(t, alertRules, 0)
+}
+
+func TestSLOGenerateAlertRulesWithLatencyTargets(t *testing.T) {
+	shortWindow := false
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Window: model.Duration(30 * 24 * time.Hour),
+			Latency: []methods.LatencyTarget{
+				{LE: "0.1", Target: 90},
+				{LE: "0.5", Target: 95},
+				{LE: "1", Target: 99},
+			},
+		},
+		LatencyRecord: ExprBlock{
+			AlertMethod: "multi-window",
+			ShortWindow: &shortWindow,
+			Windows: []methods.Window{
+				{Duration: model.Duration(time.Hour), Consumption: 2, Notification: "page"},
+				{Duration: model.Duration(24 * time.Hour), Consumption: 10, Notification: "ticket"},
+			},
+			Targets: []LatencyAlertTarget{
+				// p50 only tickets
+				{LE: "0.1", Severity: "ticket"},
+				// p95 has its own windows
+				{LE: "0.5", Windows: []methods.Window{
+					{Duration: model.Duration(6 * time.Hour), Consumption: 5, Notification: "page"},
+				}},
+			},
+		},
+	}
+
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
+
+	type alert struct {
+		Alert  string
+		Expr   string
+		Labels map[string]string
+	}
+	alerts := []alert{}
+	for _, rule := range alertRules {
+		alerts = append(alerts, alert{Alert: rule.Alert.Value, Expr: rule.Expr.Value, Labels: rule.Labels})
+	}
+
+	assert.Equal(t, []alert{
+		{
+			Alert:  "slo:my-service.latency.page",
+			Expr:   "slo:service_latency:ratio_rate_1h{le=\"1\", service=\"my-service\"} < 0.856",
+			Labels: map[string]string{"severity": "page", "signal": "latency"},
+		},
+		{
+			Alert:  "slo:my-service.latency.ticket",
+			Expr:   "slo:service_latency:ratio_rate_1d{le=\"1\", service=\"my-service\"} < 0.97",
+			Labels: map[string]string{"severity": "ticket", "signal": "latency"},
+		},
+		{
+			Alert:  "slo:my-service.latency.ticket",
+			Expr:   "slo:service_latency:ratio_rate_1d{le=\"0.1\", service=\"my-service\"} < 0.7",
+			Labels: map[string]string{"severity": "ticket", "signal": "latency", "le": "0.1", "target": "90"},
+		},
+		{
+			Alert:  "slo:my-service.latency.page",
+			Expr:   "slo:service_latency:ratio_rate_6h{le=\"0.5\", service=\"my-service\"} < 0.7",
+			Labels: map[string]string{"severity": "page", "signal": "latency", "le": "0.5", "target": "95"},
+		},
+	}, alerts)
+}
+
+func TestSLOGenerateAlertRulesWithSimpleLatencyTargets(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Latency: []methods.LatencyTarget{
+				{LE: "0.1", Target: 50},
+				{LE: "1", Target: 99},
+			},
+		},
+		LatencyRecord: ExprBlock{
+			AlertMethod: "simple",
+			AlertWindow: "1h",
+			AlertWait:   "5m",
+			Targets: []LatencyAlertTarget{
+				{LE: "0.1", AlertWindow: "1d", AlertWait: "30m", BurnRate: 2, Severity: "ticket"},
+			},
+		},
+	}
+
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, alertRules, 2)
+
+	assert.Equal(t, ruleNode(rulefmt.Rule{
+		Alert:       "slo:my-service.latency.page",
+		Expr:        "slo:service_latency:ratio_rate_1h{le=\"1\", service=\"my-service\"} < 1 - 1 * 0.01",
+		For:         model.Duration(5 * time.Minute),
+		Labels:      map[string]string{"severity": "page", "signal": "latency"},
+		Annotations: map[string]string{},
+	}), alertRules[0])
+	assert.Equal(t, ruleNode(rulefmt.Rule{
+		Alert:       "slo:my-service.latency.ticket",
+		Expr:        "slo:service_latency:ratio_rate_1d{le=\"0.1\", service=\"my-service\"} < 1 - 2 * 0.5",
+		For:         model.Duration(30 * time.Minute),
+		Labels:      map[string]string{"severity": "ticket", "signal": "latency", "le": "0.1", "target": "50"},
+		Annotations: map[string]string{},
+	}), alertRules[1])
+
+	alertRules, err = slo.GenerateAlertRules(nil, true)
+	assert.NoError(t, err)
+	assert.Len(t, alertRules, 1)
+
+	slo.LatencyRecord.Targets[0].AlertWait = "soon"
+	alertRules, err = slo.GenerateAlertRules(nil, false)
+	assert.Nil(t, alertRules)
+	assert.EqualError(t, err, "could not generate SLO \"my-service\", latency record, field targets[0].alertWait: not a valid duration string: \"soon\"")
+}
+
+func TestSLOGenerateAlertRulesPerTarget(t *testing.T) {
+	shortWindow := false
+	perTarget := true
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Window: model.Duration(30 * 24 * time.Hour),
+			Latency: []methods.LatencyTarget{
+				{LE: "0.1", Target: 99},
+				{LE: "0.2", Target: 99.9},
+			},
+		},
+		LatencyRecord: ExprBlock{
+			AlertMethod: "multi-window",
+			PerTarget:   &perTarget,
+			ShortWindow: &shortWindow,
+			Windows: []methods.Window{
+				{Duration: model.Duration(time.Hour), Consumption: 2, Notification: "page"},
+			},
+		},
+	}
+
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []rulefmt.RuleNode{
+		ruleNode(rulefmt.Rule{
+			Alert:       "slo:my-service.latency.page",
+			Expr:        "slo:service_latency:ratio_rate_1h{le=\"0.1\", service=\"my-service\"} < 0.856",
+			Labels:      map[string]string{"severity": "page", "signal": "latency", "le": "0.1", "target": "99"},
+			Annotations: map[string]string{"threshold": "0.1", "objective": "99% of requests faster than 0.1"},
+		}),
+		ruleNode(rulefmt.Rule{
+			Alert:       "slo:my-service.latency.page",
+			Expr:        "slo:service_latency:ratio_rate_1h{le=\"0.2\", service=\"my-service\"} < 0.986",
+			Labels:      map[string]string{"severity": "page", "signal": "latency", "le": "0.2", "target": "99.9"},
+			Annotations: map[string]string{"threshold": "0.2", "objective": "99.9% of requests faster than 0.2"},
+		}),
+	}, alertRules)
+
+	slo.LatencyRecord = ExprBlock{
+		AlertMethod: "simple",
+		AlertWindow: "1h",
+		PerTarget:   &perTarget,
+	}
+	alertRules, err = slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, alertRules, 2)
+	assert.Equal(t, "slo:service_latency:ratio_rate_1h{le=\"0.2\", service=\"my-service\"} < 1 - 1 * 0.001", alertRules[1].Expr.Value)
+	assert.Equal(t, map[string]string{"severity": "page", "signal": "latency", "le": "0.2", "target": "99.9"}, alertRules[1].Labels)
+}
+
+func TestSLOGenerateAlertRulesWithObjectiveMetrics(t *testing.T) {
+	slo := &SLO{
+		Name:             "my-service",
+		ObjectiveMetrics: true,
+		Objectives: Objectives{
+			Availability: 99.9,
+			Latency: []methods.LatencyTarget{
+				{LE: "0.1", Target: 95},
+			},
+		},
+		ErrorRateRecord: ExprBlock{
+			AlertMethod: "multi-window",
+		},
+		LatencyRecord: ExprBlock{
+			AlertMethod: "simple",
+			AlertWindow: "1h",
+		},
+	}
+
+	alertRules, err := slo.GenerateAlertRules(nil, true)
+	assert.NoError(t, err)
+
+	exprs := []string{}
+	for _, rule := range alertRules {
+		exprs = append(exprs, rule.Expr.Value)
+	}
+	assert.Equal(t, []string{
+		"(slo:service_errors_total:ratio_rate_1h{service=\"my-service\"} > on(service) group_left() (14.4 * (1 - slo:objective:availability{service=\"my-service\"})) and slo:service_errors_total:ratio_rate_5m{service=\"my-service\"} > on(service) group_left() (14.4 * (1 - slo:objective:availability{service=\"my-service\"}))) or (slo:service_errors_total:ratio_rate_6h{service=\"my-service\"} > on(service) group_left() (6 * (1 - slo:objective:availability{service=\"my-service\"})) and slo:service_errors_total:ratio_rate_30m{service=\"my-service\"} > on(service) group_left() (6 * (1 - slo:objective:availability{service=\"my-service\"})))",
+		"slo:service_latency:ratio_rate_1h{le=\"0.1\", service=\"my-service\"} < on(le, service) group_left() (1 - 1 * (1 - slo:objective:latency{le=\"0.1\", service=\"my-service\"}))",
+	}, exprs)
+
+	slo.ErrorRateRecord = ExprBlock{AlertMethod: "simple", AlertWindow: "1h"}
+	slo.LatencyRecord = ExprBlock{AlertMethod: "multi-window"}
+	alertRules, err = slo.GenerateAlertRules(nil, true)
+	assert.NoError(t, err)
+
+	exprs = []string{}
+	for _, rule := range alertRules {
+		exprs = append(exprs, rule.Expr.Value)
+	}
+	assert.Equal(t, []string{
+		"slo:service_errors_total:ratio_rate_1h{service=\"my-service\"} > on(service) group_left() (1 * (1 - slo:objective:availability{service=\"my-service\"}))",
+		"(slo:service_latency:ratio_rate_1h{le=\"0.1\", service=\"my-service\"} < on(le, service) group_left() (1 - 14.4 * (1 - slo:objective:latency{le=\"0.1\", service=\"my-service\"})) and slo:service_latency:ratio_rate_5m{le=\"0.1\", service=\"my-service\"} < on(le, service) group_left() (1 - 14.4 * (1 - slo:objective:latency{le=\"0.1\", service=\"my-service\"}))) or (slo:service_latency:ratio_rate_6h{le=\"0.1\", service=\"my-service\"} < on(le, service) group_left() (1 - 6 * (1 - slo:objective:latency{le=\"0.1\", service=\"my-service\"})) and slo:service_latency:ratio_rate_30m{le=\"0.1\", service=\"my-service\"} < on(le, service) group_left() (1 - 6 * (1 - slo:objective:latency{le=\"0.1\", service=\"my-service\"})))",
+	}, exprs)
+}
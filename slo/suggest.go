@@ -0,0 +1,145 @@
+package slo
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+var unknownFieldRegexp = regexp.MustCompile(`^line \d+: field (\S+) not found in type (\S+)$`)
+
+// suggestFields appends a "did you mean" hint to every unknown field error,
+// using the closest field name known by the decoded type
+func suggestFields(typeErr *yaml.TypeError, target reflect.Type) {
+	fields := map[string][]string{}
+	collectFields(target, fields)
+
+	for i, msg := range typeErr.Errors {
+		match := unknownFieldRegexp.FindStringSubmatch(msg)
+		if match == nil {
+			continue
+		}
+
+		if suggestions := closestFields(match[1], fields[match[2]]); len(suggestions) > 0 {
+			typeErr.Errors[i] = fmt.Sprintf("%s, did you mean %s?", msg, strings.Join(suggestions, " or "))
+		}
+	}
+}
+
+// collectFields maps every struct type reachable from t to its YAML field names
+func collectFields(t reflect.Type, fields map[string][]string) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	if _, ok := fields[t.String()]; ok {
+		return
+	}
+	fields[t.String()] = []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		if len(tag) > 1 && tag[1] == "inline" {
+			collectFields(field.Type, fields)
+			fields[t.String()] = append(fields[t.String()], fields[field.Type.String()]...)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fields[t.String()] = append(fields[t.String()], name)
+		collectFields(field.Type, fields)
+	}
+}
+
+// closestFields returns the known fields that look like a typo of name: the ones within
+// a small edit distance or containing each other, sorted by edit distance
+func closestFields(name string, known []string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	var (
+		candidates  []candidate
+		lowerName   = strings.ToLower(name)
+		maxDistance = len(name)/5 + 1
+	)
+
+	for _, field := range known {
+		lowerField := strings.ToLower(field)
+		distance := editDistance(lowerName, lowerField)
+		contains := len(field) > 3 && (strings.Contains(lowerName, lowerField) || strings.Contains(lowerField, lowerName))
+
+		if distance <= maxDistance || contains {
+			candidates = append(candidates, candidate{name: field, distance: distance})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	result := make([]string, len(candidates))
+	for i, c := range candidates {
+		result[i] = strconv.Quote(c.name)
+	}
+
+	return result
+}
+
+// editDistance calculates the edit distance between two strings, counting
+// insertions, deletions, substitutions and transpositions of adjacent characters
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(a)][len(b)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,124 @@
+package slo
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/globocom/slo-generator/methods"
+	"github.com/globocom/slo-generator/samples"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+)
+
+// LatencyAlertTarget overrides the alerting options of latencyRecord for one latency target,
+// options not set are inherited from latencyRecord
+type LatencyAlertTarget struct {
+	LE          string                       `yaml:"le"`
+	AlertWindow string                       `yaml:"alertWindow"`
+	BurnRate    float64                      `yaml:"burnRate"`
+	AlertWait   string                       `yaml:"alertWait"`
+	Severity    methods.NotificationSeverity `yaml:"severity"`
+	Windows     []methods.Window             `yaml:"windows"`
+	ShortWindow *bool                        `yaml:"shortWindow"`
+}
+
+// findTarget returns the alerting options of the latency target le, nil when it is not overridden
+func (block *ExprBlock) findTarget(le string) (int, *LatencyAlertTarget) {
+	for i := range block.Targets {
+		if block.Targets[i].LE == le {
+			return i, &block.Targets[i]
+		}
+	}
+
+	return -1, nil
+}
+
+// withTarget returns the block with alerting options overridden by the target
+func (block ExprBlock) withTarget(target *LatencyAlertTarget) ExprBlock {
+	if target.AlertWindow != "" {
+		block.AlertWindow = target.AlertWindow
+	}
+	if target.BurnRate != 0 {
+		block.BurnRate = target.BurnRate
+	}
+	if target.AlertWait != "" {
+		block.AlertWait = target.AlertWait
+	}
+	if target.Severity != "" {
+		block.Severity = target.Severity
+	}
+	if len(target.Windows) > 0 {
+		block.Windows = target.Windows
+	}
+	if target.ShortWindow != nil {
+		block.ShortWindow = target.ShortWindow
+	}
+	block.Targets = nil
+
+	return block
+}
+
+// latencyAlertRules alerts together all latency targets sharing the options of latencyRecord,
+// targets with their own options are alerted apart, labeled by their le
+func (slo *SLO) latencyAlertRules(method methods.AlertMethod, objectives *Objectives, sampleConfig *samples.Config) ([]rulefmt.RuleNode, error) {
+	alert := func(block ExprBlock, targets []methods.LatencyTarget) ([]rulefmt.Rule, error) {
+		return method.AlertForLatency(&methods.AlertLatencyOptions{
+			ServiceName:      slo.Name,
+			Targets:          targets,
+			RateOptions:      block.rateOptions(objectives),
+			AlertWait:        block.AlertWait,
+			PerTarget:        block.GetPerTarget(),
+			Annotate:         slo.AlertAnnotations != nil,
+			BudgetWindow:     time.Duration(objectives.budgetWindow()),
+			ObjectiveMetrics: slo.ObjectiveMetrics,
+			Samples:          sampleConfig,
+		})
+	}
+
+	var (
+		rules         []rulefmt.RuleNode
+		sharedTargets []methods.LatencyTarget
+	)
+
+	for _, target := range objectives.Latency {
+		i, alertTarget := slo.LatencyRecord.findTarget(target.LE)
+		if alertTarget == nil {
+			sharedTargets = append(sharedTargets, target)
+			continue
+		}
+
+		targetRules, err := alert(slo.LatencyRecord.withTarget(alertTarget), []methods.LatencyTarget{target})
+		if err != nil {
+			return nil, slo.generateError(LatencyBlock, targetField(i, methodField(err)), err)
+		}
+		if err := checkAlertExprs(targetRules); err != nil {
+			return nil, slo.generateError(LatencyBlock, targetField(i, "alert"), err)
+		}
+		for _, rule := range targetRules {
+			rule.Labels["le"] = target.LE
+			rule.Labels["target"] = strconv.FormatFloat(float64(target.Target), 'f', -1, 64)
+		}
+		rules = append(rules, ruleNodes(targetRules)...)
+	}
+
+	if len(sharedTargets) > 0 {
+		sharedRules, err := alert(slo.LatencyRecord, sharedTargets)
+		if err != nil {
+			return nil, slo.generateError(LatencyBlock, methodField(err), err)
+		}
+		if err := checkAlertExprs(sharedRules); err != nil {
+			return nil, slo.generateError(LatencyBlock, "alert", err)
+		}
+		rules = append(ruleNodes(sharedRules), rules...)
+	}
+
+	return rules, nil
+}
+
+func targetField(i int, field string) string {
+	if field == "" {
+		return fmt.Sprintf("targets[%d]", i)
+	}
+
+	return fmt.Sprintf("targets[%d].%s", i, field)
+}
@@ -0,0 +1,181 @@
+package slo
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/globocom/slo-generator/methods"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+)
+
+// TemplateData is given to the Go templates of annotations of alerts, like
+// "{{ .SLO.Name }} burned {{ .Window.Consumption }}% of budget". Labels only know SLO, Class and Objectives
+type TemplateData struct {
+	SLO        *SLO
+	Class      *Class // nil when the SLO has no class
+	Objectives *Objectives
+	Signal     string                 // error or latency
+	Severity   string                 // page or ticket
+	Window     methods.Window         // first window of the alert
+	Windows    []methods.Window       // all windows of the alert, with the budget consumed on each one
+	Target     *methods.LatencyTarget // latency target of alerts labeled by le
+}
+
+// templateActions match the actions rendered by the generator, the ones whose pipeline starts with
+// a field of TemplateData. Other actions, like {{ $value }}, are written to the rules as they are
+var templateActions = regexp.MustCompile(`\{\{-?\s*\.(SLO|Class|Objectives|Signal|Severity|Window|Windows|Target)\b[^}]*\}\}`)
+
+// labelActions match the actions of labels, which are shared by recording rules
+// and only know the SLO, its class and its objectives
+var labelActions = regexp.MustCompile(`\{\{-?\s*\.(SLO|Class|Objectives)\b[^}]*\}\}`)
+
+// prometheusVariables are defined by prometheus when it renders the templates of alerts
+const prometheusVariables = "{{$labels := 0}}{{$externalLabels := 0}}{{$externalURL := 0}}{{$value := 0}}"
+
+var errLabelTemplate = errors.New("labels are shared by recording rules, their templates can only use .SLO, .Class and .Objectives")
+
+// templateFuncs are available in the actions rendered by the generator, besides the builtin functions
+var templateFuncs = template.FuncMap{
+	"toUpper":      strings.ToUpper,
+	"toLower":      strings.ToLower,
+	"reReplaceAll": reReplaceAll,
+}
+
+func reReplaceAll(pattern, repl, text string) string {
+	return regexp.MustCompile(pattern).ReplaceAllString(text, repl)
+}
+
+// renderTemplate renders the actions of text matched by actions, the others are kept.
+// Trim markers, like {{- .Signal -}}, trim the text around the action
+func renderTemplate(name, text string, actions *regexp.Regexp, data *TemplateData) (string, error) {
+	var buf strings.Builder
+	last, trimNext := 0, false
+
+	for _, match := range actions.FindAllStringIndex(text, -1) {
+		action := text[match[0]:match[1]]
+		before := text[last:match[0]]
+		if trimNext {
+			before = strings.TrimLeft(before, " \t\r\n")
+		}
+		if strings.HasPrefix(action, "{{-") {
+			before = strings.TrimRight(before, " \t\r\n")
+		}
+		buf.WriteString(before)
+
+		tmpl, err := template.New(name).Option("missingkey=error").Funcs(templateFuncs).Parse(action)
+		if err != nil {
+			return "", err
+		}
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", err
+		}
+		last, trimNext = match[1], strings.HasSuffix(action, "-}}")
+	}
+
+	rest := text[last:]
+	if trimNext {
+		rest = strings.TrimLeft(rest, " \t\r\n")
+	}
+	buf.WriteString(rest)
+
+	return buf.String(), nil
+}
+
+// checkTemplate reports actions of labels and annotations that can not be parsed, and actions
+// of labels not rendered by the generator, since recording rules do not render templates
+func checkTemplate(key, name, text string) error {
+	actions := templateActions
+	if key == "labels" {
+		actions = labelActions
+		if rest := actions.ReplaceAllString(text, ""); strings.Contains(rest, "{{") {
+			return errLabelTemplate
+		}
+	}
+
+	// the whole template is parsed as prometheus does, its functions are not known here
+	tree := parse.New(key + "." + name)
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(prometheusVariables+text, "", "", map[string]*parse.Tree{}); err != nil {
+		return err
+	}
+
+	for _, action := range actions.FindAllString(text, -1) {
+		if _, err := template.New(key + "." + name).Funcs(templateFuncs).Parse(action); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renderLabels returns the SLO with the policy of its class and its labels rendered. Labels are
+// given to every rule of the SLO, so they are rendered once, before any rule is generated
+func (slo *SLO) renderLabels(sloClass *Class) (*SLO, error) {
+	merged := slo.withClass(sloClass)
+	objectives := merged.objectives(sloClass)
+	data := &TemplateData{SLO: merged, Class: sloClass, Objectives: &objectives}
+
+	result := *merged
+	result.Labels = make(map[string]string, len(merged.Labels))
+	for name, value := range merged.Labels {
+		if err := checkTemplate("labels", name, value); err != nil {
+			return nil, merged.generateError("", "labels."+name, err)
+		}
+		rendered, err := renderTemplate("labels."+name, value, labelActions, data)
+		if err != nil {
+			return nil, merged.generateError("", "labels."+name, err)
+		}
+		result.Labels[name] = rendered
+	}
+
+	return &result, nil
+}
+
+// renderAnnotations renders the templates of the annotations of an alert rule
+func (slo *SLO) renderAnnotations(rule *rulefmt.RuleNode, data *TemplateData) error {
+	for name, value := range rule.Annotations {
+		rendered, err := renderTemplate("annotations."+name, value, templateActions, data)
+		if err != nil {
+			return slo.generateError("", "annotations."+name, err)
+		}
+		rule.Annotations[name] = rendered
+	}
+
+	return nil
+}
+
+// templateData returns the data given to templates of an alert rule, found by its labels
+func (slo *SLO) templateData(rule *rulefmt.RuleNode, sloClass *Class, objectives *Objectives) *TemplateData {
+	data := &TemplateData{
+		SLO:        slo,
+		Class:      sloClass,
+		Objectives: objectives,
+		Signal:     rule.Labels["signal"],
+		Severity:   rule.Labels["severity"],
+	}
+
+	block := slo.ErrorRateRecord
+	if data.Signal == "latency" {
+		block = slo.LatencyRecord
+		if le, ok := rule.Labels["le"]; ok {
+			if _, target := slo.LatencyRecord.findTarget(le); target != nil {
+				block = block.withTarget(target)
+			}
+			for i := range objectives.Latency {
+				if objectives.Latency[i].LE == le {
+					data.Target = &objectives.Latency[i]
+				}
+			}
+		}
+	}
+
+	data.Windows = block.alertedWindows(objectives, methods.NotificationSeverity(data.Severity))
+	if len(data.Windows) > 0 {
+		data.Window = data.Windows[0]
+	}
+
+	return data
+}
@@ -0,0 +1,121 @@
+package slo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/globocom/slo-generator/methods"
+)
+
+func TestSLOGenerateAlertRulesWithTemplates(t *testing.T) {
+	perTarget := true
+	slo := &SLO{
+		Name: "my-team.my-service",
+		Objectives: Objectives{
+			Availability: 99.9,
+			Latency: []methods.LatencyTarget{
+				{LE: "0.1", Target: 95},
+			},
+		},
+		ErrorRateRecord: ExprBlock{
+			AlertMethod: "multi-window",
+		},
+		LatencyRecord: ExprBlock{
+			AlertMethod: "simple",
+			AlertWindow: "1h",
+			BurnRate:    7.2,
+			PerTarget:   &perTarget,
+		},
+		Labels: map[string]string{
+			"team": `{{ .SLO.Name | reReplaceAll "\\..*" "" }}`,
+		},
+		Annotations: map[string]string{
+			"message": "{{ .SLO.Name }} burned {{ .Window.Consumption }}% of {{ .Signal }} budget in {{ .Window.Duration }}",
+			"value":   "{{ $value | humanizePercentage }} of {{ .Objectives.Availability }}%",
+			"link":    "https://grafana/d/x?var-service={{ .SLO.Name }}&var-severity={{ .Severity }}",
+			"target":  "{{ if $labels.le }}{{ $labels.le }} of {{ .SLO.Name }}{{ end }}",
+			"query":   `{{ with query "up" }}{{ . | first | value }}{{ end }}`,
+			"static":  "no template",
+		},
+	}
+
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, alertRules, 3)
+
+	assert.Equal(t, "my-team", alertRules[0].Labels["team"])
+	assert.Equal(t, map[string]string{
+		"message": "my-team.my-service burned 2% of error budget in 1h",
+		"value":   "{{ $value | humanizePercentage }} of 99.9%",
+		"link":    "https://grafana/d/x?var-service=my-team.my-service&var-severity=page",
+		"target":  "{{ if $labels.le }}{{ $labels.le }} of my-team.my-service{{ end }}",
+		"query":   `{{ with query "up" }}{{ . | first | value }}{{ end }}`,
+		"static":  "no template",
+	}, alertRules[0].Annotations)
+
+	assert.Equal(t, "my-team.my-service burned 10% of error budget in 1d", alertRules[1].Annotations["message"])
+	assert.Equal(t, "my-team.my-service burned 1% of latency budget in 1h", alertRules[2].Annotations["message"])
+
+	// labels are rendered for recording and budget rules too
+	slo.ErrorRateRecord.Expr = "sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))"
+	groupRules, err := slo.GenerateGroupRules(nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-team", groupRules[0].Rules[0].Labels["team"])
+
+	budgetRules, err := slo.GenerateBudgetRules(nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-team", budgetRules[0].Labels["team"])
+	assert.Contains(t, budgetRules[0].Expr.Value, `team="my-team"`)
+}
+
+func TestRenderTemplate(t *testing.T) {
+	data := &TemplateData{
+		SLO:    &SLO{Name: "my-service"},
+		Signal: "error",
+		Target: &methods.LatencyTarget{LE: "0.1", Target: 99},
+	}
+
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"{{ .SLO.Name }}", "my-service"},
+		{"{{- .Signal -}} \n", "error"},
+		{"{{ $value }} {{ .SLO.Name }}", "{{ $value }} my-service"},
+		{"{{ $labels.instance | toUpper }}", "{{ $labels.instance | toUpper }}"},
+		{"{{ .SLO.Name | toUpper }}", "MY-SERVICE"},
+		{"{{ .Labels.instance }}", "{{ .Labels.instance }}"},
+		{"{{ if $labels.le }}{{ .Target.LE }}{{ end }}", "{{ if $labels.le }}0.1{{ end }}"},
+		{`{{ "{{" }} .SLO.Name }}`, `{{ "{{" }} .SLO.Name }}`},
+	}
+
+	for _, test := range tests {
+		rendered, err := renderTemplate("test", test.text, templateActions, data)
+		assert.NoError(t, err, test.text)
+		assert.Equal(t, test.expected, rendered, test.text)
+	}
+
+	_, err := renderTemplate("test", "{{ .SLO.Nome }}", templateActions, data)
+	assert.EqualError(t, err, "template: test:1:7: executing \"test\" at <.SLO.Nome>: can't evaluate field Nome in type *slo.SLO")
+}
+
+func TestSLOGenerateAlertRulesWithInvalidTemplate(t *testing.T) {
+	slo := &SLO{
+		Name: "my-service",
+		Objectives: Objectives{
+			Availability: 99.9,
+		},
+		ErrorRateRecord: ExprBlock{
+			AlertMethod: "simple",
+			AlertWindow: "1h",
+		},
+		Annotations: map[string]string{
+			"message": "{{ .Window.Size }}",
+		},
+	}
+
+	alertRules, err := slo.GenerateAlertRules(nil, false)
+	assert.Nil(t, alertRules)
+	assert.EqualError(t, err, "could not generate SLO \"my-service\", field annotations.message: template: annotations.message:1:10: executing \"annotations.message\" at <.Window.Size>: can't evaluate field Size in type methods.Window")
+}
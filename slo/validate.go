@@ -0,0 +1,562 @@
+package slo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globocom/slo-generator/methods"
+	"github.com/globocom/slo-generator/samples"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// ValidationError points to a problem found in a SLO specification
+type ValidationError struct {
+	File   string
+	Line   int
+	Column int
+	Object string
+	Field  string
+	Msg    string
+}
+
+func (e *ValidationError) Error() string {
+	var position string
+	if e.Line > 0 {
+		position = fmt.Sprintf("%d:%d: ", e.Line, e.Column)
+		if e.File != "" {
+			position = e.File + ":" + position
+		}
+	} else if e.File != "" {
+		position = e.File + ": "
+	}
+
+	msg := position + e.Object
+	if e.Field != "" {
+		msg += ": " + e.Field
+	}
+
+	return msg + ": " + e.Msg
+}
+
+// ValidationErrors holds all problems found in a SLO specification
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Validate checks the whole specification and reports every problem found,
+// instead of stopping at the first one
+func (s *SLOSpec) Validate() error {
+	v := &validator{}
+
+	if s.Samples != nil {
+		v.validateSamples(s.source, "SLO specification", path("samples"), s.Samples)
+	}
+
+	classNames := map[string]bool{}
+	for _, class := range s.Classes {
+		if class.Name != "" && classNames[class.Name] {
+			v.report(class.source, class.object(), path("name"), "class %q is defined more than once", class.Name)
+		}
+		classNames[class.Name] = true
+
+		v.validateClass(&class, s.Classes)
+	}
+
+	sloNames := map[string]bool{}
+	for _, slo := range s.SLOS {
+		if slo.Name != "" && sloNames[slo.Name] {
+			v.report(slo.source, slo.object(), path("name"), "SLO %q is defined more than once", slo.Name)
+		}
+		sloNames[slo.Name] = true
+
+		v.validateSLO(&slo, s.Classes)
+	}
+
+	if len(v.errs) > 0 {
+		sort.SliceStable(v.errs, func(i, j int) bool {
+			a, b := v.errs[i], v.errs[j]
+			if a.File != b.File {
+				return a.File < b.File
+			}
+			if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			return a.Column < b.Column
+		})
+		return v.errs
+	}
+
+	return nil
+}
+
+func (class *Class) object() string {
+	return fmt.Sprintf("class %q", class.Name)
+}
+
+func (slo *SLO) object() string {
+	return fmt.Sprintf("SLO %q", slo.Name)
+}
+
+type fieldPath []interface{}
+
+func path(elems ...interface{}) fieldPath {
+	return fieldPath(elems)
+}
+
+func (p fieldPath) with(elems ...interface{}) fieldPath {
+	result := make(fieldPath, 0, len(p)+len(elems))
+	result = append(result, p...)
+	return append(result, elems...)
+}
+
+func (p fieldPath) String() string {
+	var b strings.Builder
+	for _, elem := range p {
+		switch value := elem.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", value)
+		default:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			fmt.Fprint(&b, value)
+		}
+	}
+
+	return b.String()
+}
+
+type validator struct {
+	errs ValidationErrors
+}
+
+// report adds a problem, pointing to the deepest node of the path found in the YAML
+func (v *validator) report(src source, object string, field fieldPath, format string, args ...interface{}) {
+	err := &ValidationError{
+		File:   src.file,
+		Object: object,
+		Field:  field.String(),
+		Msg:    fmt.Sprintf(format, args...),
+	}
+
+	if src.node != nil {
+		node := src.node
+		for i := len(field); i >= 0; i-- {
+			if found := lookupNode(src.node, field[:i]...); found != nil {
+				node = found
+				break
+			}
+		}
+		err.Line = node.Line
+		err.Column = node.Column
+	}
+
+	v.errs = append(v.errs, err)
+}
+
+func (v *validator) validateClass(class *Class, classes Classes) {
+	if class.Name == "" {
+		v.report(class.source, class.object(), path("name"), "name is required")
+	}
+
+	if class.Extends != "" {
+		if _, err := classes.classChain(class.Name); err != nil {
+			v.report(class.source, class.object(), path("extends"), "%s", err.Error())
+		}
+	}
+
+	// availability may be inherited from the class extended
+	v.validateObjectives(class.source, class.object(), path("objectives"), &class.Objectives, class.Extends == "")
+
+	if class.Samples != nil {
+		v.validateSamples(class.source, class.object(), path("samples"), class.Samples)
+	}
+
+	if class.LatencyQuantileRecord != nil {
+		v.validateQuantiles(class.source, class.object(), path("latencyQuantileRecord", "quantiles"), class.LatencyQuantileRecord.Quantiles)
+	}
+
+	// options of the policy are validated with the SLOs of the class
+	policies := []struct {
+		key   string
+		block *ExprBlock
+	}{
+		{key: "errorRateRecord", block: class.ErrorRateRecord},
+		{key: "latencyRecord", block: class.LatencyRecord},
+	}
+	for _, policy := range policies {
+		if policy.block == nil {
+			continue
+		}
+
+		field := path(policy.key)
+		if policy.block.Expr != "" || policy.block.IsEventBased() || policy.block.Buckets != nil || policy.block.Quantiles != nil {
+			v.report(class.source, class.object(), field, "only alerting options can be set in classes, SLIs are defined by the SLOs")
+		}
+		if policy.key != "latencyRecord" && (policy.block.Targets != nil || policy.block.PerTarget != nil) {
+			v.report(class.source, class.object(), field, "targets and alertPerTarget are only used by latencyRecord")
+		}
+	}
+
+	v.validateTemplates(class.source, class.object(), "labels", class.Labels)
+	v.validateTemplates(class.source, class.object(), "annotations", class.Annotations)
+}
+
+func (v *validator) validateQuantiles(src source, object string, field fieldPath, quantiles []float64) {
+	names := map[string]bool{}
+	for i, quantile := range quantiles {
+		if quantile <= 0 || quantile >= 1 {
+			v.report(src, object, field.with(i), "quantile must be between 0 and 1 (exclusive), got %g", quantile)
+			continue
+		}
+
+		name := QuantileName(quantile)
+		if names[name] {
+			v.report(src, object, field.with(i), "quantile %g is defined more than once", quantile)
+		}
+		names[name] = true
+	}
+}
+
+func (v *validator) validateSLO(slo *SLO, classes Classes) {
+	if slo.Name == "" {
+		v.report(slo.source, slo.object(), path("name"), "name is required")
+	}
+
+	var sloClass *Class
+	if slo.Class != "" {
+		class, err := classes.FindClass(slo.Class)
+		if err != nil {
+			v.report(slo.source, slo.object(), path("class"), "%s", err.Error())
+		} else {
+			sloClass = class
+		}
+	}
+	// availability may be inherited from the class
+	v.validateObjectives(slo.source, slo.object(), path("objectives"), &slo.Objectives, slo.Class == "" && slo.ErrorRateRecord.AlertMethod != "")
+	// labels and annotations given by the class are validated with it
+	v.validateTemplates(slo.source, slo.object(), "labels", slo.Labels)
+	v.validateTemplates(slo.source, slo.object(), "annotations", slo.Annotations)
+
+	// alerting options are validated together with the ones given by the class
+	slo = slo.withClass(sloClass)
+	sloObjectives := slo.objectives(sloClass)
+	objectives := &sloObjectives
+
+	blocks := []struct {
+		key          string
+		block        *ExprBlock
+		placeholders []string
+		alerting     bool
+	}{
+		{key: "trafficRateRecord", block: &slo.TrafficRateRecord, placeholders: []string{"$window"}},
+		{key: "errorRateRecord", block: &slo.ErrorRateRecord, placeholders: []string{"$window"}, alerting: true},
+		{key: "latencyRecord", block: &slo.LatencyRecord, placeholders: []string{"$window", "$le"}, alerting: true},
+		{key: "latencyQuantileRecord", block: &slo.LatencyQuantileRecord, placeholders: []string{"$window", "$quantile"}},
+	}
+
+	for _, b := range blocks {
+		field := path(b.key)
+
+		if b.block.Expr != "" {
+			for _, placeholder := range b.placeholders {
+				if !strings.Contains(b.block.Expr, placeholder) {
+					v.report(slo.source, slo.object(), field.with("expr"), "expr must contain the %s placeholder", placeholder)
+				}
+			}
+		}
+
+		if b.block.Quantiles != nil {
+			if b.key == "latencyQuantileRecord" {
+				v.validateQuantiles(slo.source, slo.object(), field.with("quantiles"), b.block.Quantiles)
+			} else {
+				v.report(slo.source, slo.object(), field.with("quantiles"), "quantiles are only used by latencyQuantileRecord")
+			}
+		}
+
+		if b.block.IsEventBased() {
+			v.validateEvents(slo, field, b.block, b.key == "errorRateRecord")
+		}
+
+		if b.alerting {
+			v.validateAlerting(slo.source, slo.object(), field, b.block, objectives, slo.samples(sloClass))
+		}
+
+		if len(b.block.Targets) > 0 {
+			if b.key == "latencyRecord" {
+				v.validateLatencyTargets(slo, field.with("targets"), b.block.Targets, objectives, slo.samples(sloClass))
+			} else {
+				v.report(slo.source, slo.object(), field.with("targets"), "targets are only used by latencyRecord")
+			}
+		}
+
+		if b.block.PerTarget != nil && b.key != "latencyRecord" {
+			v.report(slo.source, slo.object(), field.with("alertPerTarget"), "alertPerTarget is only used by latencyRecord")
+		}
+	}
+
+	if slo.LatencyHistogram != nil {
+		v.validateLatencyHistogram(slo, objectives)
+	}
+
+	// budget rules select and match the series of the SLO by its labels
+	if slo.HonorLabels && len(slo.Labels) == 0 && slo.hasBudgetRules(objectives) {
+		v.report(slo.source, slo.object(), path("honorLabels"), "honorLabels requires labels identifying the series of the SLO, its budget rules would select the series of every SLO")
+	}
+
+	// alerts match the recorded objectives by the service label, not set with honorLabels
+	if slo.ObjectiveMetrics && slo.HonorLabels {
+		v.report(slo.source, slo.object(), path("objectiveMetrics"), "objectiveMetrics can not be used with honorLabels, objectives are not recorded with the service label alerts match them by")
+	}
+}
+
+// validateTemplates reports labels and annotations that are not valid Go templates
+func (v *validator) validateTemplates(src source, object string, key string, values map[string]string) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := checkTemplate(key, name, values[name]); err != nil {
+			v.report(src, object, path(key, name), "%s", err.Error())
+		}
+	}
+}
+
+func (v *validator) validateLatencyHistogram(slo *SLO, objectives *Objectives) {
+	field := path("latencyHistogram")
+	histogram := slo.LatencyHistogram
+
+	if slo.LatencyRecord.Expr != "" {
+		v.report(slo.source, slo.object(), path("latencyRecord", "expr"), "expr can not be used together with latencyHistogram")
+	}
+
+	if histogram.Metric == "" {
+		v.report(slo.source, slo.object(), field.with("metric"), "metric is required")
+	} else if !model.IsValidMetricName(model.LabelValue(histogram.Metric)) {
+		v.report(slo.source, slo.object(), field.with("metric"), "metric %q is not a valid metric name", histogram.Metric)
+	} else {
+		if _, err := parser.ParseMetricSelector(vectorSelector(histogram.Metric, histogram.matchers())); err != nil {
+			v.report(slo.source, slo.object(), field.with("selector"), "invalid selector: %s", err.Error())
+		}
+	}
+
+	for i, bucket := range histogram.Buckets {
+		if _, err := strconv.ParseFloat(bucket, 64); err != nil {
+			v.report(slo.source, slo.object(), field.with("buckets", i), "bucket must be a numeric histogram boundary, got %q", bucket)
+		}
+	}
+
+	if histogram.Native {
+		// native histograms have no fixed boundaries
+		return
+	}
+	for _, target := range objectives.Latency {
+		if !histogram.hasBoundary(target.LE) {
+			v.report(slo.source, slo.object(), field.with("buckets"), "latency target le %s is not a boundary of the histogram, boundaries: %s", target.LE, strings.Join(histogram.Buckets, ", "))
+		}
+	}
+}
+
+func (v *validator) validateEvents(slo *SLO, field fieldPath, block *ExprBlock, supported bool) {
+	if !supported {
+		v.report(slo.source, slo.object(), field.with("totalEvents"), "event based SLIs are only supported by errorRateRecord")
+		return
+	}
+
+	if block.Expr != "" {
+		v.report(slo.source, slo.object(), field.with("expr"), "expr can not be used together with goodEvents, badEvents and totalEvents")
+	}
+	if block.TotalEvents == "" {
+		v.report(slo.source, slo.object(), field.with("totalEvents"), "totalEvents is required by event based SLIs")
+	}
+	if block.GoodEvents != "" && block.BadEvents != "" {
+		v.report(slo.source, slo.object(), field.with("badEvents"), "only one of goodEvents or badEvents can be set")
+	} else if block.GoodEvents == "" && block.BadEvents == "" {
+		v.report(slo.source, slo.object(), field.with("totalEvents"), "goodEvents or badEvents is required by event based SLIs")
+	}
+
+	events := []struct {
+		key      string
+		selector string
+	}{
+		{key: "goodEvents", selector: block.GoodEvents},
+		{key: "badEvents", selector: block.BadEvents},
+		{key: "totalEvents", selector: block.TotalEvents},
+	}
+	for _, e := range events {
+		if e.selector == "" {
+			continue
+		}
+
+		expr, err := parser.ParseExpr(e.selector)
+		if err != nil {
+			v.report(slo.source, slo.object(), field.with(e.key), "invalid selector: %s", err.Error())
+			continue
+		}
+		if _, ok := expr.(*parser.VectorSelector); !ok {
+			v.report(slo.source, slo.object(), field.with(e.key), "%s must be a selector of counters, like http_requests_total{status=~\"5..\"}, got %s", e.key, e.selector)
+		}
+	}
+}
+
+func (v *validator) validateObjectives(src source, object string, field fieldPath, objectives *Objectives, requireAvailability bool) {
+	if objectives.Availability != 0 || requireAvailability {
+		if objectives.Availability <= 0 || objectives.Availability >= 100 {
+			v.report(src, object, field.with("availability"), "availability must be between 0 and 100 (exclusive), got %g", objectives.Availability)
+		}
+	}
+
+	for i, target := range objectives.Latency {
+		targetField := field.with("latency", i)
+
+		if _, err := strconv.ParseFloat(target.LE, 64); err != nil {
+			v.report(src, object, targetField.with("le"), "le must be a numeric histogram boundary, got %q", target.LE)
+		}
+		if target.Target <= 0 || target.Target >= 100 {
+			v.report(src, object, targetField.with("target"), "target must be between 0 and 100 (exclusive), got %g", target.Target)
+		}
+	}
+}
+
+func (v *validator) validateAlerting(src source, object string, field fieldPath, block *ExprBlock, objectives *Objectives, sampleConfig *samples.Config) {
+	if block.AlertMethod != "" && methods.Get(block.AlertMethod) == nil {
+		v.report(src, object, field.with("alertMethod"), "alertMethod %q is not valid, available methods: %s", block.AlertMethod, strings.Join(methods.Names(), ", "))
+	}
+
+	if block.AlertWindow != "" || block.AlertMethod == "simple" {
+		if err := sampleConfig.ValidateSample(block.AlertWindow); err != nil {
+			v.report(src, object, field.with("alertWindow"), "%s", err.Error())
+		}
+	}
+
+	if block.AlertWait != "" {
+		if _, err := model.ParseDuration(block.AlertWait); err != nil {
+			v.report(src, object, field.with("alertWait"), "%s", err.Error())
+		}
+	}
+
+	if block.Severity != "" && !validSeverity(block.Severity) {
+		v.report(src, object, field.with("severity"), "severity must be one of page, ticket, got %q", block.Severity)
+	}
+
+	if block.BurnRate < 0 {
+		v.report(src, object, field.with("burnRate"), "burnRate must be positive, got %g", block.BurnRate)
+	}
+
+	if len(block.Windows) > 0 && objectives.Window == 0 {
+		v.report(src, object, field.with("windows"), "custom windows require objectives.window to be defined, their consumption is a share of the budget over it")
+	}
+
+	for i, window := range block.Windows {
+		windowField := field.with("windows", i)
+
+		if window.Duration <= 0 {
+			v.report(src, object, windowField.with("duration"), "duration must be greater than zero")
+		} else if objectives.Window > 0 && time.Duration(window.Duration) > time.Duration(objectives.Window) {
+			v.report(src, object, windowField.with("duration"), "duration %s is longer than the SLO window %s", window.Duration, objectives.Window)
+		}
+
+		if window.Consumption <= 0 || window.Consumption > 100 {
+			v.report(src, object, windowField.with("consumption"), "consumption must be a percentage of the error budget between 0 and 100, got %g", window.Consumption)
+		}
+
+		if !validSeverity(window.Notification) {
+			v.report(src, object, windowField.with("notification"), "notification must be one of page, ticket, got %q", window.Notification)
+		}
+	}
+}
+
+func (v *validator) validateLatencyTargets(slo *SLO, field fieldPath, targets []LatencyAlertTarget, objectives *Objectives, sampleConfig *samples.Config) {
+	les := map[string]bool{}
+	for _, target := range objectives.Latency {
+		les[target.LE] = true
+	}
+
+	seen := map[string]bool{}
+	for i, target := range targets {
+		targetField := field.with(i)
+
+		if target.LE == "" {
+			v.report(slo.source, slo.object(), targetField.with("le"), "le is required")
+		} else if !les[target.LE] {
+			v.report(slo.source, slo.object(), targetField.with("le"), "le %s is not a latency objective, objectives: %s", target.LE, strings.Join(objectives.LatencyBuckets(), ", "))
+		} else if seen[target.LE] {
+			v.report(slo.source, slo.object(), targetField.with("le"), "le %s is defined more than once", target.LE)
+		}
+		seen[target.LE] = true
+
+		// only options set by the target, the inherited ones are validated with latencyRecord
+		v.validateAlerting(slo.source, slo.object(), targetField, &ExprBlock{
+			AlertWindow: target.AlertWindow,
+			BurnRate:    target.BurnRate,
+			AlertWait:   target.AlertWait,
+			Severity:    target.Severity,
+			Windows:     target.Windows,
+		}, objectives, sampleConfig)
+	}
+}
+
+func (v *validator) validateSamples(src source, object string, field fieldPath, sampleConfig *samples.Config) {
+	if len(sampleConfig.Groups) == 0 {
+		v.report(src, object, field.with("groups"), "at least one group of samples is required")
+	}
+
+	groupNames := map[string]bool{}
+	buckets := map[string]bool{}
+	for i, group := range sampleConfig.Groups {
+		groupField := field.with("groups", i)
+
+		if group.Name == "" {
+			v.report(src, object, groupField.with("name"), "name is required")
+		} else if groupNames[group.Name] {
+			v.report(src, object, groupField.with("name"), "group %q is defined more than once", group.Name)
+		}
+		groupNames[group.Name] = true
+
+		if _, err := model.ParseDuration(group.Interval); err != nil {
+			v.report(src, object, groupField.with("interval"), "%s", err.Error())
+		}
+
+		if len(group.Buckets) == 0 {
+			v.report(src, object, groupField.with("buckets"), "at least one bucket is required")
+		}
+		for j, bucket := range group.Buckets {
+			if _, err := model.ParseDuration(bucket); err != nil {
+				v.report(src, object, groupField.with("buckets", j), "%s", err.Error())
+			} else if buckets[bucket] {
+				v.report(src, object, groupField.with("buckets", j), "bucket %s is recorded more than once", bucket)
+			}
+			buckets[bucket] = true
+		}
+	}
+
+	for i, bucket := range sampleConfig.TicketBuckets {
+		if !buckets[bucket] {
+			v.report(src, object, field.with("ticketBuckets", i), "bucket %s is not recorded by any group", bucket)
+		}
+	}
+}
+
+func validSeverity(severity methods.NotificationSeverity) bool {
+	for _, s := range methods.Severities {
+		if s == severity {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,375 @@
+package slo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+classes:
+  - name: HIGH
+    objectives:
+      availability: 99.9
+slos:
+  - name: my-service
+    objectives:
+      availability: 120
+      latency:
+        - le: fast
+          target: 95
+        - le: 0.5
+          target: 100
+    errorRateRecord:
+      alertMethod: multi-windows
+      expr: sum(rate(http_errors[5m]))/sum(rate(http_total[5m]))
+    latencyRecord:
+      alertMethod: simple
+      alertWindow: 22m
+      expr: sum(rate(http_bucket[$window]))/sum(rate(http_total[$window]))
+
+  - name: my-service
+    class: LOW
+    errorRateRecord:
+      alertMethod: multi-window
+      windows:
+        - duration: 1h
+          consumption: 200
+          notification: email
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	err = spec.Validate()
+	if assert.IsType(t, ValidationErrors{}, err) {
+		assert.Equal(t, []string{
+			"slo.yml:9:21: SLO \"my-service\": objectives.availability: availability must be between 0 and 100 (exclusive), got 120",
+			"slo.yml:11:15: SLO \"my-service\": objectives.latency[0].le: le must be a numeric histogram boundary, got \"fast\"",
+			"slo.yml:14:19: SLO \"my-service\": objectives.latency[1].target: target must be between 0 and 100 (exclusive), got 100",
+			"slo.yml:16:20: SLO \"my-service\": errorRateRecord.alertMethod: alertMethod \"multi-windows\" is not valid, available methods: multi-window, simple",
+			"slo.yml:17:13: SLO \"my-service\": errorRateRecord.expr: expr must contain the $window placeholder",
+			"slo.yml:20:20: SLO \"my-service\": latencyRecord.alertWindow: Sample 22m is not a valid sample, valid samples: 5m,30m,1h,2h,6h,1d,3d",
+			"slo.yml:21:13: SLO \"my-service\": latencyRecord.expr: expr must contain the $le placeholder",
+			"slo.yml:23:11: SLO \"my-service\": name: SLO \"my-service\" is defined more than once",
+			"slo.yml:24:12: SLO \"my-service\": class: SLO class \"LOW\" is not found",
+			"slo.yml:28:9: SLO \"my-service\": errorRateRecord.windows: custom windows require objectives.window to be defined, their consumption is a share of the budget over it",
+			"slo.yml:29:24: SLO \"my-service\": errorRateRecord.windows[0].consumption: consumption must be a percentage of the error budget between 0 and 100, got 200",
+			"slo.yml:30:25: SLO \"my-service\": errorRateRecord.windows[0].notification: notification must be one of page, ticket, got \"email\"",
+		}, errorMessages(err.(ValidationErrors)))
+	}
+}
+
+func TestValidateValidSpec(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+slos:
+  - name: my-service
+    objectives:
+      availability: 99.9
+      window: 30d
+      latency:
+        - le: 0.1
+          target: 95
+    errorRateRecord:
+      alertMethod: multi-window
+      windows:
+        - duration: 1h
+          consumption: 2
+          notification: page
+      expr: sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))
+    latencyRecord:
+      alertMethod: simple
+      alertWindow: 30m
+      alertWait: 5m
+      expr: sum(rate(http_bucket{le="$le"}[$window]))/sum(rate(http_total[$window]))
+`), "slo.yml", true)
+	assert.NoError(t, err)
+	assert.NoError(t, spec.Validate())
+}
+
+func TestValidateWithoutPositions(t *testing.T) {
+	spec := &SLOSpec{
+		SLOS: []SLO{
+			{Name: "my-service", Class: "HIGH"},
+		},
+	}
+
+	assert.EqualError(t, spec.Validate(), "SLO \"my-service\": class: SLO class \"HIGH\" is not found")
+}
+
+func errorMessages(errs ValidationErrors) []string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+
+	return msgs
+}
+
+func TestValidateSamples(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+samples:
+  groups:
+    - name: short
+      interval: 30x
+      buckets: [5m, 1h]
+    - name: short
+      interval: 5m
+      buckets: [1h, 1week]
+  ticketBuckets: [1d]
+classes:
+  - name: HIGH
+    objectives:
+      availability: 99.9
+    samples:
+      groups: []
+slos:
+  - name: my-service
+    class: HIGH
+    errorRateRecord:
+      alertMethod: simple
+      alertWindow: 5m
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	err = spec.Validate()
+	if assert.IsType(t, ValidationErrors{}, err) {
+		assert.Equal(t, []string{
+			"slo.yml:5:17: SLO specification: samples.groups[0].interval: not a valid duration string: \"30x\"",
+			"slo.yml:7:13: SLO specification: samples.groups[1].name: group \"short\" is defined more than once",
+			"slo.yml:9:17: SLO specification: samples.groups[1].buckets[0]: bucket 1h is recorded more than once",
+			"slo.yml:9:21: SLO specification: samples.groups[1].buckets[1]: not a valid duration string: \"1week\"",
+			"slo.yml:10:19: SLO specification: samples.ticketBuckets[0]: bucket 1d is not recorded by any group",
+			"slo.yml:16:15: class \"HIGH\": samples.groups: at least one group of samples is required",
+			"slo.yml:22:20: SLO \"my-service\": errorRateRecord.alertWindow: Sample 5m is not a valid sample, valid samples: ",
+		}, errorMessages(err.(ValidationErrors)))
+	}
+}
+
+func TestValidateEvents(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+slos:
+  - name: my-service
+    objectives:
+      availability: 99.9
+    errorRateRecord:
+      expr: sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))
+      goodEvents: http_total{status!~"5.."}
+      badEvents: http_total{status=~"5.."}
+  - name: other-service
+    objectives:
+      availability: 99.9
+    errorRateRecord:
+      badEvents: rate(http_total{status=~"5.."}[5m])
+    trafficRateRecord:
+      totalEvents: http_total
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	err = spec.Validate()
+	if assert.IsType(t, ValidationErrors{}, err) {
+		assert.Equal(t, []string{
+			"slo.yml:7:7: SLO \"my-service\": errorRateRecord.totalEvents: totalEvents is required by event based SLIs",
+			"slo.yml:7:13: SLO \"my-service\": errorRateRecord.expr: expr can not be used together with goodEvents, badEvents and totalEvents",
+			"slo.yml:9:18: SLO \"my-service\": errorRateRecord.badEvents: only one of goodEvents or badEvents can be set",
+			"slo.yml:14:7: SLO \"other-service\": errorRateRecord.totalEvents: totalEvents is required by event based SLIs",
+			"slo.yml:14:18: SLO \"other-service\": errorRateRecord.badEvents: badEvents must be a selector of counters, like http_requests_total{status=~\"5..\"}, got rate(http_total{status=~\"5..\"}[5m])",
+			"slo.yml:16:20: SLO \"other-service\": trafficRateRecord.totalEvents: event based SLIs are only supported by errorRateRecord",
+		}, errorMessages(err.(ValidationErrors)))
+	}
+}
+
+func TestValidateQuantiles(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+classes:
+  - name: HIGH
+    objectives:
+      availability: 99.9
+    latencyQuantileRecord:
+      quantiles: [0.9, 99]
+slos:
+  - name: my-service
+    latencyQuantileRecord:
+      expr: histogram_quantile($quantile, sum by (le) (rate(http_bucket[$window])))
+      quantiles: [0.999, 0.9990]
+    latencyRecord:
+      quantiles: [0.5]
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	err = spec.Validate()
+	if assert.IsType(t, ValidationErrors{}, err) {
+		assert.Equal(t, []string{
+			"slo.yml:7:24: class \"HIGH\": latencyQuantileRecord.quantiles[1]: quantile must be between 0 and 1 (exclusive), got 99",
+			"slo.yml:12:26: SLO \"my-service\": latencyQuantileRecord.quantiles[1]: quantile 0.999 is defined more than once",
+			"slo.yml:14:18: SLO \"my-service\": latencyRecord.quantiles: quantiles are only used by latencyQuantileRecord",
+		}, errorMessages(err.(ValidationErrors)))
+	}
+}
+
+func TestValidateLatencyTargets(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+slos:
+  - name: my-service
+    objectives:
+      latency:
+        - le: 0.1
+          target: 95
+    errorRateRecord:
+      severity: email
+      alertPerTarget: true
+      targets:
+        - le: 0.1
+    latencyRecord:
+      alertMethod: multi-window
+      targets:
+        - le: 0.1
+          alertWait: soon
+        - le: 0.1
+          burnRate: -1
+        - le: 0.2
+          severity: ticket
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	err = spec.Validate()
+	if assert.IsType(t, ValidationErrors{}, err) {
+		assert.Equal(t, []string{
+			"slo.yml:9:17: SLO \"my-service\": errorRateRecord.severity: severity must be one of page, ticket, got \"email\"",
+			"slo.yml:10:23: SLO \"my-service\": errorRateRecord.alertPerTarget: alertPerTarget is only used by latencyRecord",
+			"slo.yml:12:9: SLO \"my-service\": errorRateRecord.targets: targets are only used by latencyRecord",
+			"slo.yml:17:22: SLO \"my-service\": latencyRecord.targets[0].alertWait: not a valid duration string: \"soon\"",
+			"slo.yml:18:15: SLO \"my-service\": latencyRecord.targets[1].le: le 0.1 is defined more than once",
+			"slo.yml:19:21: SLO \"my-service\": latencyRecord.targets[1].burnRate: burnRate must be positive, got -1",
+			"slo.yml:20:15: SLO \"my-service\": latencyRecord.targets[2].le: le 0.2 is not a latency objective, objectives: 0.1",
+		}, errorMessages(err.(ValidationErrors)))
+	}
+}
+
+func TestValidateHonorLabels(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+slos:
+  - name: all-services
+    honorLabels: true
+    objectives:
+      availability: 99.9
+    errorRateRecord:
+      expr: sum by (service) (rate(http_errors[$window])) / sum by (service) (rate(http_total[$window]))
+  - name: my-service
+    honorLabels: true
+    labels:
+      team: search
+    objectives:
+      availability: 99.9
+    errorRateRecord:
+      expr: sum(rate(http_errors[$window])) / sum(rate(http_total[$window]))
+  - name: quantiles
+    honorLabels: true
+    latencyQuantileRecord:
+      expr: histogram_quantile($quantile, sum by (le, service) (rate(http_bucket[$window])))
+  - name: objective-metrics
+    honorLabels: true
+    objectiveMetrics: true
+    labels:
+      team: search
+    objectives:
+      availability: 99.9
+    errorRateRecord:
+      expr: sum(rate(http_errors[$window])) / sum(rate(http_total[$window]))
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	err = spec.Validate()
+	if assert.IsType(t, ValidationErrors{}, err) {
+		assert.Equal(t, []string{
+			"slo.yml:4:18: SLO \"all-services\": honorLabels: honorLabels requires labels identifying the series of the SLO, its budget rules would select the series of every SLO",
+			"slo.yml:23:23: SLO \"objective-metrics\": objectiveMetrics: objectiveMetrics can not be used with honorLabels, objectives are not recorded with the service label alerts match them by",
+		}, errorMessages(err.(ValidationErrors)))
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+slos:
+  - name: my-service
+    labels:
+      team: "{{ .SLO.Name "
+      tier: "{{ .Class.Name | lower }}"
+      severity: "{{ .Severity }}"
+    annotations:
+      message: "{{ $value | humanize }} {{ .SLO.Name }}"
+      link: "{{ .SLO.Name | printf \"%s }}"
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	err = spec.Validate()
+	if assert.IsType(t, ValidationErrors{}, err) {
+		assert.Equal(t, []string{
+			"slo.yml:5:13: SLO \"my-service\": labels.team: labels are shared by recording rules, their templates can only use .SLO, .Class and .Objectives",
+			"slo.yml:6:13: SLO \"my-service\": labels.tier: template: labels.tier:1: function \"lower\" not defined",
+			"slo.yml:7:17: SLO \"my-service\": labels.severity: labels are shared by recording rules, their templates can only use .SLO, .Class and .Objectives",
+			"slo.yml:10:13: SLO \"my-service\": annotations.link: template: annotations.link:1: unterminated quoted string",
+		}, errorMessages(err.(ValidationErrors)))
+	}
+}
+
+func TestValidateClassExtends(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+classes:
+  - name: HIGH
+    extends: BASE
+  - name: A
+    extends: A
+    objectives:
+      availability: 99.9
+slos:
+  - name: my-service
+    class: HIGH
+    objectives:
+      availability: 100
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	err = spec.Validate()
+	if assert.IsType(t, ValidationErrors{}, err) {
+		assert.Equal(t, []string{
+			"slo.yml:4:14: class \"HIGH\": extends: SLO class \"HIGH\" extends \"BASE\", which is not found",
+			"slo.yml:6:14: class \"A\": extends: SLO class \"A\" extends itself: A -> A",
+			"slo.yml:11:12: SLO \"my-service\": class: SLO class \"HIGH\" extends \"BASE\", which is not found",
+			"slo.yml:13:21: SLO \"my-service\": objectives.availability: availability must be between 0 and 100 (exclusive), got 100",
+		}, errorMessages(err.(ValidationErrors)))
+	}
+}
+
+func TestValidateClassPolicy(t *testing.T) {
+	spec, err := ParseSLOSpec([]byte(`
+classes:
+  - name: HIGH
+    objectives:
+      availability: 99.9
+    errorRateRecord:
+      alertMethod: simple
+      alertWindow: 22m
+      expr: sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))
+      targets:
+        - le: 0.1
+    annotations:
+      message: "{{ .SLO.Name "
+slos:
+  - name: my-service
+    class: HIGH
+    errorRateRecord:
+      expr: sum(rate(http_errors[$window]))/sum(rate(http_total[$window]))
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	err = spec.Validate()
+	if assert.IsType(t, ValidationErrors{}, err) {
+		assert.Equal(t, []string{
+			"slo.yml:7:7: class \"HIGH\": errorRateRecord: only alerting options can be set in classes, SLIs are defined by the SLOs",
+			"slo.yml:7:7: class \"HIGH\": errorRateRecord: targets and alertPerTarget are only used by latencyRecord",
+			"slo.yml:13:16: class \"HIGH\": annotations.message: template: annotations.message:1: unclosed action",
+			"slo.yml:18:7: SLO \"my-service\": errorRateRecord.alertWindow: Sample 22m is not a valid sample, valid samples: 5m,30m,1h,2h,6h,1d,3d",
+			"slo.yml:18:7: SLO \"my-service\": errorRateRecord.targets: targets are only used by latencyRecord",
+		}, errorMessages(err.(ValidationErrors)))
+	}
+}
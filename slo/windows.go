@@ -0,0 +1,310 @@
+package slo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/globocom/slo-generator/methods"
+	"github.com/globocom/slo-generator/samples"
+	"github.com/prometheus/common/model"
+)
+
+// windowReference is a recorded window used by an alert
+type windowReference struct {
+	alert  string
+	signal string
+	window string
+}
+
+// alertRates is a block alerting a SLI, with the windows and burn rates of each severity
+type alertRates struct {
+	signal  string // ErrorBlock or LatencyBlock
+	le      string // latency target alerted apart with its own options, empty otherwise
+	block   ExprBlock
+	targets []methods.LatencyTarget // latency targets alerted by the block
+	rates   map[methods.NotificationSeverity][]methods.MultiRateWindow
+}
+
+// rateOptions returns the options choosing the windows alerted by the block
+func (block *ExprBlock) rateOptions(objectives *Objectives) methods.RateOptions {
+	return methods.RateOptions{
+		SLOWindow:   time.Duration(objectives.Window),
+		Windows:     block.Windows,
+		ShortWindow: block.GetShortWindow(),
+		BurnRate:    block.BurnRate,
+		AlertWindow: block.AlertWindow,
+		Severity:    block.Severity,
+	}
+}
+
+// rateWindow returns the long window of a burn rate alerted for a severity, with the budget
+// consumed on it, false when the window is not a duration
+func rateWindow(rate methods.MultiRateWindow, severity methods.NotificationSeverity, budgetWindow time.Duration) (methods.Window, bool) {
+	duration, err := model.ParseDuration(rate.LongWindow)
+	if err != nil {
+		return methods.Window{}, false
+	}
+	consumption := rate.Multiplier * float64(duration) / float64(budgetWindow) * 100
+
+	return methods.Window{
+		Duration:     duration,
+		Consumption:  methods.Percent(math.Round(consumption*1e6) / 1e6),
+		Notification: severity,
+	}, true
+}
+
+// alertedWindows returns the windows alerted by the block for a severity, as its alert method builds them
+func (block *ExprBlock) alertedWindows(objectives *Objectives, severity methods.NotificationSeverity) []methods.Window {
+	method := methods.Get(block.AlertMethod)
+	if method == nil {
+		return nil
+	}
+	options := block.rateOptions(objectives)
+
+	var windows []methods.Window
+	for _, rate := range method.Rates(&options)[severity] {
+		if window, ok := rateWindow(rate, severity, time.Duration(objectives.budgetWindow())); ok {
+			windows = append(windows, window)
+		}
+	}
+
+	return windows
+}
+
+// alertRates returns the windows and burn rates alerted by the blocks of the SLO, as the alert
+// methods build their rules from them. Blocks with an unknown method are reported on generation
+func (slo *SLO) alertRates(objectives *Objectives) []alertRates {
+	var result []alertRates
+	add := func(signal, le string, block ExprBlock, targets []methods.LatencyTarget) {
+		method := methods.Get(block.AlertMethod)
+		if method == nil {
+			return
+		}
+		options := block.rateOptions(objectives)
+		result = append(result, alertRates{signal: signal, le: le, block: block, targets: targets, rates: method.Rates(&options)})
+	}
+
+	if slo.ErrorRateRecord.AlertMethod != "" {
+		add(ErrorBlock, "", slo.ErrorRateRecord, nil)
+	}
+
+	if slo.LatencyRecord.AlertMethod != "" && objectives.Latency != nil {
+		var sharedTargets []methods.LatencyTarget
+		for _, target := range objectives.Latency {
+			if _, alertTarget := slo.LatencyRecord.findTarget(target.LE); alertTarget == nil {
+				sharedTargets = append(sharedTargets, target)
+			}
+		}
+		if len(sharedTargets) > 0 {
+			add(LatencyBlock, "", slo.LatencyRecord, sharedTargets)
+		}
+		for _, target := range objectives.Latency {
+			if _, alertTarget := slo.LatencyRecord.findTarget(target.LE); alertTarget != nil {
+				add(LatencyBlock, target.LE, slo.LatencyRecord.withTarget(alertTarget), []methods.LatencyTarget{target})
+			}
+		}
+	}
+
+	return result
+}
+
+// alertWindows returns every recorded window referenced by the alerts of the SLO,
+// without building the alerts
+func (slo *SLO) alertWindows(sloClass *Class, disableTicket bool) []windowReference {
+	objectives := slo.objectives(sloClass)
+
+	var references []windowReference
+	for _, alert := range slo.alertRates(&objectives) {
+		name := "slo:" + slo.Name + ".errors."
+		if alert.signal == LatencyBlock {
+			name = "slo:" + slo.Name + ".latency."
+		}
+
+		for _, severity := range methods.Severities {
+			if disableTicket && severity == methods.NotificationTicketSeverity {
+				continue
+			}
+			for _, rate := range alert.rates[severity] {
+				for _, window := range []string{rate.LongWindow, rate.ShortWindow} {
+					if window != "" {
+						references = append(references, windowReference{alert: name + string(severity), signal: alert.signal, window: window})
+					}
+				}
+			}
+		}
+	}
+
+	return references
+}
+
+// sampleGroups returns the groups of windows recorded for the SLO: the configured samples plus
+// the windows derived from alerts, without ticket windows when tickets are disabled
+func (slo *SLO) sampleGroups(sloClass *Class, disableTicket bool) ([]samples.Sample, error) {
+	sampleConfig := slo.samples(sloClass)
+
+	references := slo.alertWindows(sloClass, disableTicket)
+	windows := make([]string, len(references))
+	alerted := map[string]bool{}
+	for i, reference := range references {
+		windows[i] = reference.window
+		alerted[reference.window] = true
+	}
+	groups, err := deriveSamples(sampleConfig.Groups, windows)
+	if err != nil {
+		return nil, slo.generateError("", "windows", err)
+	}
+
+	recorded := map[string]bool{}
+	for i, group := range groups {
+		var buckets []string
+		for _, bucket := range group.Buckets {
+			// ticket buckets are still recorded when alerts of other severities use them
+			if disableTicket && sampleConfig.IsTicketSample(bucket) && !alerted[bucket] {
+				continue
+			}
+			buckets = append(buckets, bucket)
+			recorded[bucket] = true
+		}
+		groups[i].Buckets = buckets
+	}
+
+	if err := slo.checkRecordedWindows(references, recorded); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// derivedSample is the group of windows longer than every bucket of the samples,
+// evaluated at least every derivedInterval, like the daily group of the default samples
+const (
+	derivedSample   = "derived"
+	derivedInterval = model.Duration(5 * time.Minute)
+)
+
+// deriveSamples adds the windows not recorded by any group of samples, each window goes
+// to the group with the shortest buckets able to hold it, so it is evaluated at a
+// similar interval of its neighbours. Longer windows go to a group of their own
+func deriveSamples(groups []samples.Sample, windows []string) ([]samples.Sample, error) {
+	result := make([]samples.Sample, len(groups))
+	recorded := map[string]bool{}
+	for i, group := range groups {
+		result[i] = group
+		result[i].Buckets = append([]string{}, group.Buckets...)
+
+		for _, bucket := range group.Buckets {
+			recorded[bucket] = true
+		}
+	}
+
+	for _, window := range windows {
+		if recorded[window] {
+			continue
+		}
+		recorded[window] = true
+
+		duration, err := model.ParseDuration(window)
+		if err != nil {
+			return nil, err
+		}
+
+		target := -1
+		for i, group := range result {
+			longest, err := longestBucket(group.Buckets)
+			if err != nil {
+				return nil, err
+			}
+			if duration <= longest && group.Name != derivedSample {
+				target = i
+				break
+			}
+		}
+		if target < 0 {
+			if target, err = derivedGroup(&result); err != nil {
+				return nil, err
+			}
+		}
+
+		result[target].Buckets = append(result[target].Buckets, window)
+		if err := sortBuckets(result[target].Buckets); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// derivedGroup returns the index of the group of long windows, adding it when missing,
+// its interval is the longest of the other groups
+func derivedGroup(groups *[]samples.Sample) (int, error) {
+	interval := derivedInterval
+	for i, group := range *groups {
+		if group.Name == derivedSample {
+			return i, nil
+		}
+
+		duration, err := model.ParseDuration(group.Interval)
+		if err != nil {
+			return 0, err
+		}
+		if duration > interval {
+			interval = duration
+		}
+	}
+
+	*groups = append(*groups, samples.Sample{Name: derivedSample, Interval: interval.String()})
+	return len(*groups) - 1, nil
+}
+
+func longestBucket(buckets []string) (model.Duration, error) {
+	var longest model.Duration
+	for _, bucket := range buckets {
+		duration, err := model.ParseDuration(bucket)
+		if err != nil {
+			return 0, err
+		}
+		if duration > longest {
+			longest = duration
+		}
+	}
+
+	return longest, nil
+}
+
+func sortBuckets(buckets []string) error {
+	durations := map[string]time.Duration{}
+	for _, bucket := range buckets {
+		duration, err := model.ParseDuration(bucket)
+		if err != nil {
+			return err
+		}
+		durations[bucket] = time.Duration(duration)
+	}
+
+	sort.SliceStable(buckets, func(i, j int) bool {
+		return durations[buckets[i]] < durations[buckets[j]]
+	})
+
+	return nil
+}
+
+// checkRecordedWindows fails when an alert refers to a window of a signal that is not recorded
+func (slo *SLO) checkRecordedWindows(references []windowReference, recorded map[string]bool) error {
+	for _, reference := range references {
+		hasSLI := slo.ErrorRateRecord.HasSLI()
+		if reference.signal == LatencyBlock {
+			hasSLI = slo.hasLatencySLI()
+		}
+
+		// SLIs without expression are recorded outside of the generator
+		if !hasSLI || recorded[reference.window] {
+			continue
+		}
+
+		return slo.generateError(reference.signal, "windows", fmt.Errorf("alert %s refers to window %s which is not recorded", reference.alert, reference.window))
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+// Package synthetic builds synthetic counters for the SLIs of SLOs, so rules can be evaluated
+// at error ratios chosen without the real series
+package synthetic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/globocom/slo-generator/slo"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// Counters are the selectors of the counters an error SLI is computed from
+type Counters struct {
+	part  []*labels.Matcher // bad events, or good events when good is set
+	good  bool
+	total []*labels.Matcher
+}
+
+// ErrorCounters finds the counters of the error SLI, given by events or by an expr dividing
+// the aggregated rate of a counter by the aggregated rate of another one
+func ErrorCounters(block *slo.ExprBlock) (*Counters, error) {
+	if block.IsEventBased() {
+		part, good := block.BadEvents, false
+		if part == "" {
+			part, good = block.GoodEvents, true
+		}
+
+		partMatchers, err := parser.ParseMetricSelector(part)
+		if err != nil {
+			return nil, err
+		}
+		totalMatchers, err := parser.ParseMetricSelector(block.TotalEvents)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Counters{part: partMatchers, good: good, total: totalMatchers}, nil
+	}
+
+	expr, err := parser.ParseExpr(block.ComputeExpr("5m", ""))
+	if err != nil {
+		return nil, err
+	}
+	for {
+		paren, ok := expr.(*parser.ParenExpr)
+		if !ok {
+			break
+		}
+		expr = paren.Expr
+	}
+
+	division, ok := expr.(*parser.BinaryExpr)
+	if !ok || division.Op != parser.DIV {
+		return nil, errors.New("expr is not a division of the rates of two counters")
+	}
+	part, err := counterSelector(division.LHS)
+	if err != nil {
+		return nil, err
+	}
+	total, err := counterSelector(division.RHS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Counters{part: part, total: total}, nil
+}
+
+// counterSelector returns the matchers of the only selector of a side of the SLI division, which
+// must be the rate of a counter aggregated without grouping, so the SLI has no labels of its own
+func counterSelector(expr parser.Expr) ([]*labels.Matcher, error) {
+	var selectors []*parser.VectorSelector
+	aggregated, rated := false, false
+
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.VectorSelector:
+			selectors = append(selectors, n)
+		case *parser.AggregateExpr:
+			aggregated = aggregated || (len(n.Grouping) == 0 && !n.Without)
+		case *parser.Call:
+			switch n.Func.Name {
+			case "rate", "irate", "increase":
+				rated = true
+			}
+		}
+		return nil
+	})
+
+	if len(selectors) != 1 {
+		return nil, fmt.Errorf("%s has %d selectors, only one is supported", expr, len(selectors))
+	}
+	if !rated || !aggregated {
+		return nil, fmt.Errorf("%s is not the rate of a counter aggregated without grouping", expr)
+	}
+
+	return selectors[0].LabelMatchers, nil
+}
+
+// Series is a synthetic counter increased by a constant rate
+type Series struct {
+	Labels    labels.Labels
+	PerMinute float64
+}
+
+// Series returns the counters whose requests have the error ratio given,
+// with requestsPerMinute requests in total
+func (c *Counters) Series(errorRatio, requestsPerMinute float64) ([]Series, error) {
+	partLabels, err := seriesLabels(c.part)
+	if err != nil {
+		return nil, err
+	}
+	totalLabels, err := seriesLabels(c.total)
+	if err != nil {
+		return nil, err
+	}
+	if labels.Equal(partLabels, totalLabels) {
+		return nil, errors.New("the counters of errors and of requests are the same series")
+	}
+	if matchesAll(c.part, totalLabels) {
+		return nil, fmt.Errorf("the series %s of requests is counted as errors too", Series{Labels: totalLabels})
+	}
+
+	partRate := errorRatio * requestsPerMinute
+	if c.good {
+		partRate = requestsPerMinute - partRate
+	}
+
+	// the requests of the part are counted by the selector of all requests, like
+	// status="5xx" among job="api", the series of the others is then added
+	totalRate := requestsPerMinute
+	if matchesAll(c.total, partLabels) {
+		totalRate -= partRate
+	}
+
+	return []Series{
+		{Labels: partLabels, PerMinute: round(partRate)},
+		{Labels: totalLabels, PerMinute: round(totalRate)},
+	}, nil
+}
+
+// String writes the series as its metric name followed by its labels, like input_series of promtool
+func (s Series) String() string {
+	name := s.Labels.Get(labels.MetricName)
+	others := labels.NewBuilder(s.Labels).Del(labels.MetricName).Labels()
+	if len(others) == 0 {
+		return name
+	}
+
+	return name + others.String()
+}
+
+// seriesLabels returns the labels of a series selected by the matchers, building
+// values of regular expressions from their first alternative
+func seriesLabels(matchers []*labels.Matcher) (labels.Labels, error) {
+	builder := labels.NewBuilder(nil)
+	for _, m := range matchers {
+		switch m.Type {
+		case labels.MatchEqual:
+			builder.Set(m.Name, m.Value)
+		case labels.MatchRegexp:
+			value, err := regexpValue(m.Value)
+			if err != nil {
+				return nil, err
+			}
+			builder.Set(m.Name, value)
+		}
+	}
+
+	lbls := builder.Labels()
+	if !matchesAll(matchers, lbls) {
+		return nil, fmt.Errorf("no series could be built for the selector %s", matchersString(matchers))
+	}
+
+	return lbls, nil
+}
+
+func matchesAll(matchers []*labels.Matcher, lbls labels.Labels) bool {
+	for _, m := range matchers {
+		if !m.Matches(lbls.Get(m.Name)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchersString(matchers []*labels.Matcher) string {
+	parts := make([]string, len(matchers))
+	for i, m := range matchers {
+		parts[i] = m.String()
+	}
+
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// regexpValue returns a value matched by a regular expression of a label matcher
+func regexpValue(expr string) (string, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if !writeMatch(&b, re.Simplify()) {
+		return "", fmt.Errorf("no value matches the regular expression %q", expr)
+	}
+
+	return b.String(), nil
+}
+
+func writeMatch(b *strings.Builder, re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return false
+		}
+		b.WriteRune(re.Rune[0])
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteRune('x')
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpStar, syntax.OpQuest:
+	case syntax.OpCapture, syntax.OpPlus, syntax.OpAlternate:
+		return writeMatch(b, re.Sub[0])
+	case syntax.OpRepeat:
+		for i := 0; i < re.Min; i++ {
+			if !writeMatch(b, re.Sub[0]) {
+				return false
+			}
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !writeMatch(b, sub) {
+				return false
+			}
+		}
+	default:
+		return false
+	}
+
+	return true
+}
+
+// round removes the noise of multiplications, so rates are written as given
+func round(value float64) float64 {
+	return math.Round(value*1e9) / 1e9
+}
@@ -0,0 +1,95 @@
+package synthetic
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/globocom/slo-generator/slo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountersSeries(t *testing.T) {
+	testCases := []struct {
+		name     string
+		block    slo.ExprBlock
+		expected []string
+		err      string
+	}{
+		{
+			name:  "errors among requests",
+			block: slo.ExprBlock{Expr: `sum(rate(http_total{job="api", code=~"5.."}[$window])) / sum(rate(http_total{job="api"}[$window]))`},
+			expected: []string{
+				`http_total{code="5xx", job="api"} 0+100`,
+				`http_total{job="api"} 0+900`,
+			},
+		},
+		{
+			name:  "errors and requests counted apart",
+			block: slo.ExprBlock{Expr: `sum(rate(http_errors_total[$window])) / sum(rate(http_requests_total[$window]))`},
+			expected: []string{
+				`http_errors_total 0+100`,
+				`http_requests_total 0+1000`,
+			},
+		},
+		{
+			name:  "good events",
+			block: slo.ExprBlock{GoodEvents: `grpc_total{code=~"OK|NotFound"}`, TotalEvents: `grpc_total`},
+			expected: []string{
+				`grpc_total{code="OK"} 0+900`,
+				`grpc_total 0+100`,
+			},
+		},
+		{
+			name:  "errors counting all requests",
+			block: slo.ExprBlock{BadEvents: `http_total{code!="200"}`, TotalEvents: `http_total`},
+			err:   `the counters of errors and of requests are the same series`,
+		},
+		{
+			name:  "selectors without rate",
+			block: slo.ExprBlock{Expr: `sum(errors) / sum(requests)`},
+			err:   `sum(errors) is not the rate of a counter aggregated without grouping`,
+		},
+		{
+			name:  "not a division",
+			block: slo.ExprBlock{Expr: `1 - sum(rate(ok[$window])) / sum(rate(requests[$window]))`},
+			err:   `expr is not a division of the rates of two counters`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			sli, err := ErrorCounters(&testCase.block)
+			if err == nil {
+				var inputs []Series
+				inputs, err = sli.Series(0.1, 1000)
+				var got []string
+				for _, input := range inputs {
+					got = append(got, input.String()+" 0+"+strconv.FormatFloat(input.PerMinute, 'f', -1, 64))
+				}
+				assert.Equal(t, testCase.expected, got)
+			}
+			if testCase.err != "" {
+				assert.EqualError(t, err, testCase.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRegexpValue(t *testing.T) {
+	for expr, expected := range map[string]string{
+		`5..`:           "5xx",
+		`2\d\d|3\d\d`:   "200",
+		`(GET|POST)`:    "GET",
+		`api-[a-z]+`:    "api-a",
+		`.*`:            "",
+		`[0-9]{3}`:      "000",
+		`prod(uction)?`: "prod",
+	} {
+		value, err := regexpValue(expr)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value, expr)
+	}
+}
@@ -0,0 +1,62 @@
+package unittest
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/template"
+)
+
+// templateDefs are prepended by prometheus to the labels and annotations of alerts
+const templateDefs = "{{$labels := .Labels}}{{$externalLabels := .ExternalLabels}}{{$externalURL := .ExternalURL}}{{$value := .Value}}"
+
+// queryFunc runs the queries of templates without series, the input series are not known here
+var queryFunc = rules.EngineQueryFunc(
+	promql.NewEngine(promql.EngineOpts{MaxSamples: 50000, Timeout: time.Minute}),
+	storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		return storage.NoopQuerier(), nil
+	}),
+)
+
+// expectedAlert returns the labels and annotations of an alert firing, as prometheus expands
+// them: $labels are the labels of the error ratio recorded and $value is the error ratio
+func expectedAlert(rule rulefmt.RuleNode, recordLabels map[string]string, errorRatio float64, evalTime time.Duration) (Alert, error) {
+	alert := Alert{ExpLabels: map[string]string{}}
+	for name, value := range recordLabels {
+		alert.ExpLabels[name] = value
+	}
+
+	expand := func(name, text string) (string, error) {
+		data := template.AlertTemplateData(recordLabels, nil, "", errorRatio)
+		expander := template.NewTemplateExpander(context.Background(), templateDefs+text, name, data,
+			model.Time(evalTime/time.Millisecond), template.QueryFunc(queryFunc), &url.URL{})
+		return expander.Expand()
+	}
+
+	for name, text := range rule.Labels {
+		value, err := expand("__alert_"+rule.Alert.Value, text)
+		if err != nil {
+			return Alert{}, err
+		}
+		alert.ExpLabels[name] = value
+	}
+
+	if len(rule.Annotations) > 0 {
+		alert.ExpAnnotations = map[string]string{}
+	}
+	for name, text := range rule.Annotations {
+		value, err := expand("__alert_"+rule.Alert.Value, text)
+		if err != nil {
+			return Alert{}, err
+		}
+		alert.ExpAnnotations[name] = value
+	}
+
+	return alert, nil
+}
@@ -0,0 +1,217 @@
+// Package unittest generates unit tests of the error alerts of SLOs, run by promtool test rules:
+// synthetic counters of the SLI are fed at different error ratios, asserting which alerts fire
+package unittest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globocom/slo-generator/slo"
+	"github.com/globocom/slo-generator/synthetic"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+)
+
+const (
+	interval          = time.Minute
+	requestsPerMinute = 1000.0
+
+	// evalMargin is waited after the longest for of the alerts, so every alert expected fired
+	evalMargin = 10 * time.Minute
+)
+
+// File is a unit tests file of promtool
+type File struct {
+	RuleFiles          []string       `yaml:"rule_files"`
+	EvaluationInterval model.Duration `yaml:"evaluation_interval"`
+	Tests              []TestGroup    `yaml:"tests"`
+}
+
+// TestGroup is a scenario of input series, with the alerts expected
+type TestGroup struct {
+	Name           string          `yaml:"name"`
+	Interval       model.Duration  `yaml:"interval"`
+	InputSeries    []InputSeries   `yaml:"input_series"`
+	AlertRuleTests []AlertRuleTest `yaml:"alert_rule_test"`
+}
+
+type InputSeries struct {
+	Series string `yaml:"series"`
+	Values string `yaml:"values"`
+}
+
+// AlertRuleTest lists the alerts firing at eval_time, none when ExpAlerts is empty
+type AlertRuleTest struct {
+	EvalTime  model.Duration `yaml:"eval_time"`
+	Alertname string         `yaml:"alertname"`
+	ExpAlerts []Alert        `yaml:"exp_alerts"`
+}
+
+type Alert struct {
+	ExpLabels      map[string]string `yaml:"exp_labels"`
+	ExpAnnotations map[string]string `yaml:"exp_annotations,omitempty"`
+}
+
+// errorAlert is an error alert generated for a severity
+type errorAlert struct {
+	rule  rulefmt.RuleNode
+	value float64 // lowest error ratio firing the alert
+}
+
+// Generate returns the scenarios testing the error alerts of a SLO: without errors, slightly
+// above each error ratio alerted, and slightly below the lowest one
+func Generate(s *slo.SLO, sloClass *slo.Class, disableTicket bool) ([]TestGroup, error) {
+	alerts, err := errorAlerts(s, sloClass, disableTicket)
+	if err != nil || len(alerts) == 0 {
+		return nil, err
+	}
+
+	sli, err := synthetic.ErrorCounters(&s.ErrorRateRecord)
+	if err != nil {
+		return nil, fmt.Errorf("SLO %q: %w", s.Name, err)
+	}
+	recordLabels, err := recordedLabels(s, sloClass, disableTicket)
+	if err != nil {
+		return nil, err
+	}
+
+	evalTime := time.Duration(0)
+	for _, alert := range alerts {
+		if wait := time.Duration(alert.rule.For); wait > evalTime {
+			evalTime = wait
+		}
+	}
+	evalTime += evalMargin
+
+	var groups []TestGroup
+	for _, errorRatio := range scenarios(alerts) {
+		inputs, err := sli.Series(errorRatio, requestsPerMinute)
+		if err != nil {
+			return nil, fmt.Errorf("SLO %q: %w", s.Name, err)
+		}
+
+		group := TestGroup{
+			Name:     fmt.Sprintf("%s: %s%% of errors", s.Name, strconv.FormatFloat(round(errorRatio*100), 'f', -1, 64)),
+			Interval: model.Duration(interval),
+		}
+		for _, input := range inputs {
+			group.InputSeries = append(group.InputSeries, InputSeries{
+				Series: input.String(),
+				Values: fmt.Sprintf("0+%sx%d", strconv.FormatFloat(input.PerMinute, 'f', -1, 64), int(evalTime/interval)),
+			})
+		}
+
+		for _, alert := range alerts {
+			test := AlertRuleTest{
+				EvalTime:  model.Duration(evalTime),
+				Alertname: alert.rule.Alert.Value,
+				ExpAlerts: []Alert{},
+			}
+			fires := errorRatio > alert.value
+			if fires {
+				expected, err := expectedAlert(alert.rule, recordLabels, errorRatio, evalTime)
+				if err != nil {
+					return nil, fmt.Errorf("SLO %q: %w", s.Name, err)
+				}
+				test.ExpAlerts = append(test.ExpAlerts, expected)
+			}
+
+			// alerts waiting are still pending when their for elapsed since the first
+			// evaluation, the rates need two samples to be computed
+			if wait := time.Duration(alert.rule.For); fires && wait > 0 {
+				group.AlertRuleTests = append(group.AlertRuleTests, AlertRuleTest{
+					EvalTime:  model.Duration(wait),
+					Alertname: alert.rule.Alert.Value,
+					ExpAlerts: []Alert{},
+				})
+			}
+			group.AlertRuleTests = append(group.AlertRuleTests, test)
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// errorAlerts returns the error alerts of a SLO by severity, with the lowest error ratio firing each one
+func errorAlerts(s *slo.SLO, sloClass *slo.Class, disableTicket bool) ([]errorAlert, error) {
+	values := map[string]float64{}
+	for _, threshold := range s.AlertThresholds(sloClass, disableTicket) {
+		if threshold.Signal != "error" {
+			continue
+		}
+		severity := string(threshold.Severity)
+		if value, ok := values[severity]; !ok || threshold.Value < value {
+			values[severity] = threshold.Value
+		}
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	rules, err := s.GenerateAlertRules(sloClass, disableTicket)
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []errorAlert
+	for _, rule := range rules {
+		value, ok := values[rule.Labels["severity"]]
+		if rule.Labels["signal"] != "error" || !ok {
+			continue
+		}
+		alerts = append(alerts, errorAlert{rule: rule, value: value})
+	}
+
+	return alerts, nil
+}
+
+// recordedLabels returns the labels of the error ratio recorded, which alerts keep
+func recordedLabels(s *slo.SLO, sloClass *slo.Class, disableTicket bool) (map[string]string, error) {
+	groups, err := s.GenerateGroupRules(sloClass, disableTicket)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		for _, rule := range group.Rules {
+			if strings.HasPrefix(rule.Record.Value, "slo:service_errors_total:ratio_rate_") {
+				return rule.Labels, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("SLO %q: the error ratio is not recorded", s.Name)
+}
+
+// scenarios returns the error ratios tested: none, 10% above the ratio firing each alert and
+// 10% below the lowest one, which fires no alert. Ratios above 100% can not be fed
+func scenarios(alerts []errorAlert) []float64 {
+	values := []float64{}
+	for _, alert := range alerts {
+		values = append(values, alert.value)
+	}
+	sort.Float64s(values)
+
+	ratios := []float64{0, round(values[0] * 0.9)}
+	for i, value := range values {
+		if i > 0 && value == values[i-1] {
+			continue
+		}
+		if ratio := round(value * 1.1); ratio <= 1 {
+			ratios = append(ratios, ratio)
+		}
+	}
+
+	return ratios
+}
+
+// round removes the noise of multiplications, so ratios are written as given
+func round(value float64) float64 {
+	return math.Round(value*1e9) / 1e9
+}
@@ -0,0 +1,138 @@
+package unittest
+
+import (
+	"testing"
+
+	"github.com/globocom/slo-generator/slo"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	spec, err := slo.ParseSLOSpec([]byte(`
+slos:
+  - name: api
+    objectives:
+      availability: 99.5
+    annotations:
+      summary: 'errors at {{ $value | humanizePercentage }} for {{ $labels.service }}'
+    errorRateRecord:
+      alertMethod: simple
+      alertWindow: 1h
+      alertWait: 5m
+      burnRate: 2
+      severity: page
+      badEvents: 'http_requests_total{job="api", code=~"5.."}'
+      totalEvents: 'http_requests_total{job="api"}'
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	groups, err := Generate(&spec.SLOS[0], nil, false)
+	assert.NoError(t, err)
+	assert.Len(t, groups, 3)
+
+	assert.Equal(t, "api: 0.9% of errors", groups[1].Name)
+	assert.Equal(t, []AlertRuleTest{
+		{EvalTime: model.Duration(15 * 60e9), Alertname: "slo:api.errors.page", ExpAlerts: []Alert{}},
+	}, groups[1].AlertRuleTests)
+
+	assert.Equal(t, TestGroup{
+		Name:     "api: 1.1% of errors",
+		Interval: model.Duration(60e9),
+		InputSeries: []InputSeries{
+			{Series: `http_requests_total{code="5xx", job="api"}`, Values: "0+11x15"},
+			{Series: `http_requests_total{job="api"}`, Values: "0+989x15"},
+		},
+		AlertRuleTests: []AlertRuleTest{
+			{EvalTime: model.Duration(5 * 60e9), Alertname: "slo:api.errors.page", ExpAlerts: []Alert{}},
+			{
+				EvalTime:  model.Duration(15 * 60e9),
+				Alertname: "slo:api.errors.page",
+				ExpAlerts: []Alert{{
+					ExpLabels: map[string]string{
+						"service":  "api",
+						"severity": "page",
+						"signal":   "error",
+					},
+					ExpAnnotations: map[string]string{
+						"summary": "errors at 1.1% for api",
+					},
+				}},
+			},
+		},
+	}, groups[2])
+}
+
+func TestGenerateMultiWindow(t *testing.T) {
+	spec, err := slo.ParseSLOSpec([]byte(`
+slos:
+  - name: api
+    objectives:
+      availability: 99
+    errorRateRecord:
+      alertMethod: multi-window
+      expr: |
+        sum(rate(http_requests_total{job="api", status="5xx"}[$window])) /
+        sum(rate(http_requests_total{job="api"}[$window]))
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	groups, err := Generate(&spec.SLOS[0], nil, false)
+	assert.NoError(t, err)
+
+	var names []string
+	firing := map[string][]string{}
+	for _, group := range groups {
+		names = append(names, group.Name)
+		for _, test := range group.AlertRuleTests {
+			if len(test.ExpAlerts) > 0 {
+				firing[group.Name] = append(firing[group.Name], test.Alertname)
+			}
+		}
+	}
+
+	assert.Equal(t, []string{
+		"api: 0% of errors",
+		"api: 0.9% of errors",
+		"api: 1.1% of errors",
+		"api: 6.6% of errors",
+	}, names)
+	assert.Equal(t, map[string][]string{
+		"api: 1.1% of errors": {"slo:api.errors.ticket"},
+		"api: 6.6% of errors": {"slo:api.errors.page", "slo:api.errors.ticket"},
+	}, firing)
+}
+
+func TestGenerateUnsupportedSLI(t *testing.T) {
+	spec, err := slo.ParseSLOSpec([]byte(`
+slos:
+  - name: api
+    objectives:
+      availability: 99
+    errorRateRecord:
+      alertMethod: multi-window
+      expr: |
+        sum by (job) (rate(http_requests_total{status="5xx"}[$window])) /
+        sum by (job) (rate(http_requests_total[$window]))
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	_, err = Generate(&spec.SLOS[0], nil, false)
+	assert.EqualError(t, err, `SLO "api": sum by(job) (rate(http_requests_total{status="5xx"}[5m])) is not the rate of a counter aggregated without grouping`)
+}
+
+func TestGenerateWithoutErrorAlerts(t *testing.T) {
+	spec, err := slo.ParseSLOSpec([]byte(`
+slos:
+  - name: api
+    objectives:
+      availability: 99
+    errorRateRecord:
+      expr: sum(rate(errors[$window])) / sum(rate(requests[$window]))
+`), "slo.yml", true)
+	assert.NoError(t, err)
+
+	groups, err := Generate(&spec.SLOS[0], nil, false)
+	assert.NoError(t, err)
+	assert.Empty(t, groups)
+}